@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// redacted replaces secrets on /config.
+const redacted = "<secret>"
+
+// newAdminMux returns the handler of the admin listener: health, config
+// reload, pausing collection, profiling, the last GA responses, errors,
+// series counts, label values, build provenance and the running config.
+func newAdminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.Handle("/-/reload", adminAuth(reloadHandler))
+	// Stopping collection is only offered to whoever has the token.
+	if config.AdminToken != "" {
+		mux.Handle("/-/pause", adminAuth(pauseHandler(true)))
+		mux.Handle("/-/resume", adminAuth(pauseHandler(false)))
+	}
+	mux.HandleFunc("/config", configHandler)
+	mux.HandleFunc("/debug/ga", debugGAHandler)
+	mux.HandleFunc("/errors", errorsHandler)
+	mux.HandleFunc("/cardinality", cardinalityHandler(true))
+	mux.HandleFunc("/labels", labelsHandler)
+	mux.HandleFunc("/buildinfo", buildInfoHandler)
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// adminAuth requires the admin token as a bearer token for an admin
+// action, when one is configured. Pause and resume are not served without.
+func adminAuth(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if config.AdminToken != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="ganalytics-admin"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		h(w, r)
+	})
+}
+
+// reloadHandler reloads the config file, as SIGHUP does.
+func reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := reload(); err != nil {
+		http.Error(w, fmt.Sprintf("config reload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, "config reloaded")
+}
+
+// configHandler serves the running config, secrets redacted.
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	viewsMu.RLock()
+	c := *config
+	viewsMu.RUnlock()
+
+	c.Tenants = nil
+	for _, tc := range config.Tenants {
+		tc := *tc
+		if tc.Token != "" {
+			tc.Token = redacted
+		}
+		c.Tenants = append(c.Tenants, &tc)
+	}
+	if c.GoogleAds != nil && c.GoogleAds.DeveloperToken != "" {
+		ads := *c.GoogleAds
+		ads.DeveloperToken = redacted
+		c.GoogleAds = &ads
+	}
+	if c.VictoriaMetrics != nil && (c.VictoriaMetrics.Password != "" || c.VictoriaMetrics.BearerToken != "") {
+		vm := *c.VictoriaMetrics
+		if vm.Password != "" {
+			vm.Password = redacted
+		}
+		if vm.BearerToken != "" {
+			vm.BearerToken = redacted
+		}
+		c.VictoriaMetrics = &vm
+	}
+	if c.Consul != nil && c.Consul.Token != "" {
+		cc := *c.Consul
+		cc.Token = redacted
+		c.Consul = &cc
+	}
+	if c.AdminToken != "" {
+		c.AdminToken = redacted
+	}
+	if c.FreshnessWebhook != "" {
+		c.FreshnessWebhook = redacted
+	}
+	if u, err := url.Parse(c.Proxy); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			c.Proxy = u.String()
+		}
+	}
+
+	data, err := yaml.Marshal(&c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(data)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/exporter-toolkit/web"
+)
+
+// aggregate serves the metrics of several exporters, e.g. of a sharded
+// fleet, merged on a single endpoint. It returns the exit status.
+func aggregate(args []string) int {
+	fs := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	targets := fs.String("targets", "", "Comma separated metrics URLs of the exporters merged.")
+	addr := fs.String("listen", ":9100", "Address the merged metrics are served on.")
+	path := fs.String("path", "/metrics", "Path the merged metrics are served on.")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout of the scrape of an exporter.")
+	fs.Parse(args)
+
+	if *targets == "" {
+		fmt.Fprintln(os.Stderr, "aggregate: -targets is required")
+		return 2
+	}
+	if err := web.Validate(*webConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "aggregate: web.config.file: %v\n", err)
+		return 1
+	}
+
+	a := newAggregator(strings.Split(*targets, ","), *timeout)
+	mux := http.NewServeMux()
+	mux.Handle(*path, promhttp.HandlerFor(a, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	}))
+
+	ls, err := listen("tcp", []string{*addr}, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "aggregate: %v\n", err)
+		return 1
+	}
+	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
+	if err := web.ServeMultiple(ls, &http.Server{Handler: mux}, &web.FlagConfig{WebConfigFile: webConfig}, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "aggregate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// aggregator scrapes exporters on every gather and merges their metrics.
+// Series with the same name and labels are de-duplicated, the first target
+// listing them wins, e.g. for views collected by two shards during a
+// rebalancing.
+type aggregator struct {
+	targets []string
+	client  *http.Client
+
+	registry *prometheus.Registry
+	up       *prometheus.GaugeVec
+	duration *prometheus.GaugeVec
+}
+
+func newAggregator(targets []string, timeout time.Duration) *aggregator {
+	a := &aggregator{
+		targets:  targets,
+		client:   &http.Client{Timeout: timeout},
+		registry: prometheus.NewRegistry(),
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ga_aggregate_target_up",
+			Help: "Whether the last scrape of an exporter succeeded.",
+		}, []string{"target"}),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ga_aggregate_target_scrape_duration_seconds",
+			Help: "Duration of the last scrape of an exporter.",
+		}, []string{"target"}),
+	}
+	a.registry.MustRegister(a.up, a.duration)
+	return a
+}
+
+// Gather implements prometheus.Gatherer, scraping all targets at once. The
+// up and duration metrics of the scrapes are merged first, once they are
+// set.
+func (a *aggregator) Gather() ([]*dto.MetricFamily, error) {
+	results := make([]map[string]*dto.MetricFamily, len(a.targets))
+	var wg sync.WaitGroup
+	for i, target := range a.targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			start := time.Now()
+			mfs, err := a.scrape(target)
+			a.duration.WithLabelValues(target).Set(time.Since(start).Seconds())
+			if err != nil {
+				log.Printf("aggregate: %s: %v", target, err)
+				a.up.WithLabelValues(target).Set(0)
+				return
+			}
+			a.up.WithLabelValues(target).Set(1)
+			results[i] = mfs
+		}(i, target)
+	}
+	wg.Wait()
+
+	own, err := a.registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+	ownByName := make(map[string]*dto.MetricFamily, len(own))
+	for _, mf := range own {
+		ownByName[mf.GetName()] = mf
+	}
+	results = append([]map[string]*dto.MetricFamily{ownByName}, results...)
+	sources := append([]string{"aggregate"}, a.targets...)
+
+	merged := make(map[string]*dto.MetricFamily)
+	seen := make(map[string]bool)
+	for i, mfs := range results {
+		// Families of a target are merged in name order, for a stable
+		// output.
+		names := make([]string, 0, len(mfs))
+		for name := range mfs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			mf := mfs[name]
+			m, ok := merged[name]
+			if !ok {
+				m = &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type}
+				merged[name] = m
+			}
+			if m.GetType() != mf.GetType() {
+				log.Printf("aggregate: %s: %s is a %s, not a %s as on other targets", sources[i], name, mf.GetType(), m.GetType())
+				continue
+			}
+			for _, metric := range mf.Metric {
+				id := seriesID(name, metric.Label)
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				m.Metric = append(m.Metric, metric)
+			}
+		}
+	}
+
+	out := make([]*dto.MetricFamily, 0, len(merged))
+	for _, mf := range merged {
+		out = append(out, mf)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].GetName() < out[j].GetName() })
+	return out, nil
+}
+
+// scrape fetches and parses the metrics of a target.
+func (a *aggregator) scrape(target string) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.FmtText))
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	var p expfmt.TextParser
+	return p.TextToMetricFamilies(resp.Body)
+}
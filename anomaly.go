@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Defaults of the anomaly baselines.
+const (
+	defaultAnomalyWeeks      = 4
+	defaultAnomalyMinSamples = 30
+)
+
+// anomalyConf enables anomaly scores for metrics of a view, against a
+// rolling baseline per hour of the week, so traffic abnormally low for a
+// Tuesday morning is a plain threshold alert.
+type anomalyConf struct {
+	// Metrics scored, of the metrics of the view.
+	Metrics []string `yaml:"metrics"`
+	// Weeks the baseline of an hour of the week is averaged over. Defaults
+	// to 4.
+	Weeks int `yaml:"weeks"`
+	// MinSamples of an hour of the week before it is scored. Defaults to 30.
+	MinSamples int `yaml:"min_samples"`
+}
+
+// baseline is the exponentially weighted mean and variance of a series for
+// every hour of the week.
+type baseline [7 * 24]struct {
+	n              int
+	mean, variance float64
+}
+
+// score returns the z-score of a value against the hour of the week of t
+// and adds it to the baseline. ok is false until the hour has minSamples.
+func (b *baseline) score(t time.Time, valf float64, maxSamples, minSamples int) (z float64, ok bool) {
+	h := &b[int(t.Weekday())*24+t.Hour()]
+	if h.n >= minSamples && h.variance > 0 {
+		z, ok = (valf-h.mean)/math.Sqrt(h.variance), true
+	}
+
+	// Averaged over all samples until maxSamples, weighted towards the
+	// recent ones after.
+	if h.n < maxSamples {
+		h.n++
+	}
+	alpha := 1 / float64(h.n)
+	diff := valf - h.mean
+	h.mean += alpha * diff
+	h.variance = (1 - alpha) * (h.variance + alpha*diff*diff)
+	return z, ok
+}
+
+// scored reports whether a metric of the view has anomaly scores.
+func (vc *viewConf) scored(metric string) bool {
+	if vc.Anomaly == nil {
+		return false
+	}
+	for _, m := range vc.Anomaly.Metrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// observe scores a value of the series name and labels of metric, exported
+// as the metric name with a _zscore suffix.
+func (v *view) observe(metric, name string, labelNames, labelValues []string, valf float64) error {
+	if !v.scored(metric) {
+		return nil
+	}
+	// The baseline sample count covering the weeks of an hour of the week,
+	// at the poll interval of the metric in this hour.
+	maxSamples := int(time.Duration(v.Anomaly.Weeks) * time.Hour / v.interval(metric, time.Now()))
+	if maxSamples < 1 {
+		maxSamples = 1
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key := name + "\xff" + strings.Join(labelValues, "\xff")
+	b, ok := v.baselines[key]
+	if !ok {
+		b = new(baseline)
+		v.baselines[key] = b
+	}
+	z, ok := b.score(time.Now(), valf, maxSamples, v.Anomaly.MinSamples)
+	if !ok {
+		return nil
+	}
+
+	vec, ok := v.zscoreVec[name]
+	if !ok {
+		mname, err := v.metricName(name)
+		if err != nil {
+			return err
+		}
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        mname + "_zscore",
+			Help:        fmt.Sprintf("Deviations of Google Analytics %s from its baseline for the hour of the week", name),
+			ConstLabels: v.constLabels(),
+		}, labelNames)
+		if err := v.registry.Register(vec); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		v.zscoreVec[name] = vec
+	}
+	g, err := vec.GetMetricWithLabelValues(labelValues...)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	g.Set(z)
+	return nil
+}
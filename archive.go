@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+// defaultArchiveInterval is an hour.
+const defaultArchiveInterval = 3600
+
+// archiveConf defines the periodic upload of the exposition.
+type archiveConf struct {
+	// Destination is a gs://bucket/prefix or s3://bucket/prefix URL, or a
+	// local directory.
+	Destination string `yaml:"destination"`
+	// Interval in seconds between uploads. Defaults to an hour.
+	Interval int `yaml:"interval"`
+}
+
+// archiveCollector uploads the exposition of all metrics, of every tenant,
+// with timestamped keys, <YYYY>/<MM>/<DD>/<YYYYMMDD>T<hhmmss>Z.prom, for
+// historical diffing and compliance archiving without a TSDB.
+type archiveCollector struct {
+	conf    *archiveConf
+	store   objectStore
+	lastRun time.Time
+}
+
+func newArchiveCollector(httpClient *http.Client, ac *archiveConf) (*archiveCollector, error) {
+	store, err := newObjectStore(httpClient, ac.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("exposition_archive: %v", err)
+	}
+	return &archiveCollector{conf: ac, store: store}, nil
+}
+
+func (a *archiveCollector) name() string { return "exposition_archive" }
+
+// collect uploads the exposition once the interval elapsed.
+func (a *archiveCollector) collect() error {
+	if time.Since(a.lastRun) < time.Duration(a.conf.Interval)*time.Second {
+		return nil
+	}
+	a.lastRun = time.Now()
+
+	mfs, err := allGatherers().Gather()
+	if err != nil && len(mfs) == 0 {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, mf := range mfs {
+		if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+			return err
+		}
+	}
+
+	now := a.lastRun.UTC()
+	key := now.Format("2006/01/02/20060102T150405Z") + ".prom"
+	return a.store.put(key, buf.Bytes(), string(expfmt.FmtText))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditLog records every GA query issued, nil when auditing is disabled.
+var auditLog *auditLogger
+
+// auditEntry is a single line of the audit log.
+type auditEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Cycle      string    `json:"cycle,omitempty"`
+	ViewID     string    `json:"viewid"`
+	Metric     string    `json:"metric"`
+	Dimensions string    `json:"dimensions,omitempty"`
+	Rows       int       `json:"rows"`
+	Duration   float64   `json:"duration_seconds"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// auditLogger appends JSON lines to a file or stdout.
+type auditLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// newAuditLogger opens path for appending, "-" writes to stdout.
+func newAuditLogger(path string) (*auditLogger, error) {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+
+	return &auditLogger{enc: json.NewEncoder(w)}, nil
+}
+
+// record writes an entry for a query started at start.
+func (a *auditLogger) record(e auditEntry, start time.Time, err error) {
+	if a == nil {
+		return
+	}
+
+	e.Timestamp = start.UTC()
+	e.Cycle = cycleID()
+	e.Duration = time.Since(start).Seconds()
+	e.Status = "ok"
+	if err != nil {
+		e.Status = "error"
+		e.Error = err.Error()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enc.Encode(e)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/bigquery/v2"
+)
+
+// bigqueryBatchSize is the number of rows streamed per request, as
+// recommended by BigQuery.
+const bigqueryBatchSize = 500
+
+// bigqueryConf defines the table collected values are streamed to. Its
+// schema is timestamp TIMESTAMP, view STRING, metric STRING, labels STRING
+// (JSON) and value FLOAT64.
+type bigqueryConf struct {
+	Project string `yaml:"project"`
+	Dataset string `yaml:"dataset"`
+	Table   string `yaml:"table"`
+}
+
+var (
+	bigqueryRows = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_bigquery_rows_total",
+		Help: "Rows streamed to BigQuery.",
+	})
+	bigqueryFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_bigquery_failed_rows_total",
+		Help: "Rows that failed to be streamed to BigQuery.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(bigqueryRows, bigqueryFailures)
+}
+
+// bigquerySink streams the values of the views collected in a cycle to a
+// BigQuery table, to be joined with the warehouse data.
+type bigquerySink struct {
+	conf *bigqueryConf
+	svc  *bigquery.Service
+	// mu serializes writes, a slow write delays the next one rather than
+	// piling up.
+	mu sync.Mutex
+}
+
+func newBigquerySink(httpClient *http.Client, bc *bigqueryConf) (*bigquerySink, error) {
+	svc, err := bigquery.New(httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("bigquery: %v", err)
+	}
+	return &bigquerySink{conf: bc, svc: svc}, nil
+}
+
+// write streams the current values of the GA metrics of views, at ts.
+func (b *bigquerySink) write(ts time.Time, vs []*view) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var rows []*bigquery.TableDataInsertAllRequestRows
+	for _, v := range vs {
+		mfs, err := v.registry.Gather()
+		if err != nil {
+			log.Printf("bigquery: view %s: %v", v.displayName(), err)
+		}
+		for _, mf := range mfs {
+			if strings.HasPrefix(mf.GetName(), "ga_exporter_") {
+				continue
+			}
+			for _, m := range mf.Metric {
+				rows = append(rows, bigqueryRow(ts, v.ViewID, mf.GetName(), m))
+			}
+		}
+	}
+
+	for len(rows) > 0 {
+		n := len(rows)
+		if n > bigqueryBatchSize {
+			n = bigqueryBatchSize
+		}
+		b.insert(rows[:n])
+		rows = rows[n:]
+	}
+}
+
+// insert streams a batch of rows.
+func (b *bigquerySink) insert(rows []*bigquery.TableDataInsertAllRequestRows) {
+	resp, err := b.svc.Tabledata.InsertAll(b.conf.Project, b.conf.Dataset, b.conf.Table, &bigquery.TableDataInsertAllRequest{
+		Rows: rows,
+	}).Do()
+	if err != nil {
+		log.Printf("bigquery: %v", err)
+		bigqueryFailures.Add(float64(len(rows)))
+		return
+	}
+	for _, e := range resp.InsertErrors {
+		for _, ep := range e.Errors {
+			log.Printf("bigquery: row %d: %s: %s", e.Index, ep.Reason, ep.Message)
+		}
+	}
+	bigqueryFailures.Add(float64(len(resp.InsertErrors)))
+	bigqueryRows.Add(float64(len(rows) - len(resp.InsertErrors)))
+}
+
+// bigqueryRow returns the row of a sample. Its insert ID is derived from
+// the series and timestamp, so BigQuery drops a retried duplicate.
+func bigqueryRow(ts time.Time, viewID, name string, m *dto.Metric) *bigquery.TableDataInsertAllRequestRows {
+	labels := make(map[string]string)
+	for _, l := range m.Label {
+		if l.GetName() != "job" && l.GetName() != "viewid" {
+			labels[l.GetName()] = l.GetValue()
+		}
+	}
+	data, _ := json.Marshal(labels)
+
+	var value float64
+	switch {
+	case m.Gauge != nil:
+		value = m.Gauge.GetValue()
+	case m.Counter != nil:
+		value = m.Counter.GetValue()
+	case m.Untyped != nil:
+		value = m.Untyped.GetValue()
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d/%s", ts.UnixNano(), seriesID(name, m.Label))))
+	return &bigquery.TableDataInsertAllRequestRows{
+		InsertId: hex.EncodeToString(sum[:]),
+		Json: map[string]bigquery.JsonValue{
+			"timestamp": ts.UTC().Format(time.RFC3339Nano),
+			"view":      viewID,
+			"metric":    name,
+			"labels":    string(data),
+			"value":     value,
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// boundsConf rejects implausible values of a metric, GA occasionally
+// returns absurd momentary spikes.
+type boundsConf struct {
+	// Min and Max clamp the values.
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+	// MaxChange in percent of the previous value, larger changes are
+	// rejected and the previous value kept.
+	MaxChange float64 `yaml:"max_change"`
+	// MaxRejections in a row after which a change is taken as real.
+	// Defaults to 1, a single sample spike.
+	MaxRejections int `yaml:"max_rejections"`
+}
+
+// check reports inconsistent bounds.
+func (b *boundsConf) check() error {
+	if b.Min != nil && b.Max != nil && *b.Min > *b.Max {
+		return fmt.Errorf("min %g is above max %g", *b.Min, *b.Max)
+	}
+	if b.MaxChange < 0 {
+		return fmt.Errorf("max_change %g is negative", b.MaxChange)
+	}
+	return nil
+}
+
+// seriesGuard is the state of the spike rejection of a series.
+type seriesGuard struct {
+	last     float64
+	rejected int
+}
+
+// newRejectedVecs returns the debug series of the values clamped or
+// rejected by the bounds of a view.
+func newRejectedVecs(v *view) (*prometheus.GaugeVec, *prometheus.CounterVec) {
+	value := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_exporter_rejected_value",
+		Help:        "Last raw value of a series clamped or rejected by the metric bounds.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric", "series"})
+	total := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "ga_exporter_rejected_samples_total",
+		Help:        "Values clamped or rejected by the metric bounds, by reason: min, max or spike.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric", "reason"})
+	return value, total
+}
+
+// guard applies the bounds of metric to a value of the series name and
+// labels, returning the value to export. The raw value of clamped and
+// rejected samples is exported on a debug series.
+func (v *view) guard(metric, name string, labelNames, labelValues []string, valf float64) float64 {
+	b, ok := v.Bounds[metric]
+	if !ok || b == nil {
+		return valf
+	}
+
+	pairs := make([]string, len(labelNames))
+	for i := range labelNames {
+		pairs[i] = labelNames[i] + "=" + labelValues[i]
+	}
+	series := strings.Join(pairs, ",")
+	reject := func(reason string) {
+		v.rejectedValue.WithLabelValues(name, series).Set(valf)
+		v.rejectedSamples.WithLabelValues(name, reason).Inc()
+	}
+
+	raw := valf
+	switch {
+	case b.Min != nil && valf < *b.Min:
+		reject("min")
+		valf = *b.Min
+	case b.Max != nil && valf > *b.Max:
+		reject("max")
+		valf = *b.Max
+	}
+	if b.MaxChange <= 0 {
+		return valf
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key := name + "\xff" + strings.Join(labelValues, "\xff")
+	g, ok := v.guards[key]
+	if !ok {
+		v.guards[key] = &seriesGuard{last: valf}
+		return valf
+	}
+	if g.last != 0 && math.Abs(valf-g.last)/math.Abs(g.last)*100 > b.MaxChange && g.rejected < b.MaxRejections {
+		g.rejected++
+		valf = raw
+		reject("spike")
+		return g.last
+	}
+	g.last, g.rejected = valf, 0
+	return valf
+}
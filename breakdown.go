@@ -0,0 +1,112 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/analytics/v3"
+)
+
+// maxQueryMetrics is the most metrics a RealTime query accepts.
+const maxQueryMetrics = 10
+
+var breakdownName = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// breakdownConf exports a metric by another set of dimensions than its
+// declaration, e.g. active users by country and by device, under the name
+// of the metric suffixed with _<name>.
+type breakdownConf struct {
+	// Name suffixes the metric name, e.g. by_country.
+	Name string `yaml:"name"`
+	// Dimensions the metric is broken down by.
+	Dimensions []string `yaml:"dimensions"`
+	// Mapping of the response columns, labeled after the dimensions by
+	// default. Only the column of the metric is exported.
+	Mapping *mapping `yaml:"mapping"`
+}
+
+// breakdownKey returns the name a breakdown of metric is scheduled,
+// reported and exported under, e.g. rt:activeUsers_by_country.
+func breakdownKey(metric, name string) string {
+	return metric + "_" + name
+}
+
+// breakdown returns the metric and breakdown of a breakdown key, a nil
+// breakdown for a declared metric.
+func (vc *viewConf) breakdown(key string) (string, *breakdownConf) {
+	for metric, bs := range vc.Breakdowns {
+		for _, b := range bs {
+			if breakdownKey(metric, b.Name) == key {
+				return metric, b
+			}
+		}
+	}
+	return "", nil
+}
+
+// baseMetric returns the metric of a breakdown key, the key itself for a
+// declared metric. Bounds, precision and the other per metric settings
+// apply to the breakdowns of the metric.
+func (vc *viewConf) baseMetric(key string) string {
+	if metric, b := vc.breakdown(key); b != nil {
+		return metric
+	}
+	return key
+}
+
+// queries returns the declared metrics of the view followed by the keys of
+// its breakdowns, each polled on its own schedule.
+func (vc *viewConf) queries() []string {
+	keys := append([]string(nil), vc.Metrics...)
+	metrics := make([]string, 0, len(vc.Breakdowns))
+	for metric := range vc.Breakdowns {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	for _, metric := range metrics {
+		for _, b := range vc.Breakdowns[metric] {
+			keys = append(keys, breakdownKey(metric, b.Name))
+		}
+	}
+	return keys
+}
+
+// queryMetrics returns the metrics requested by the query of key. The
+// breakdowns of the view with the same dimensions request all their
+// metrics, the identical queries of a cycle being issued once.
+func (vc *viewConf) queryMetrics(key string) string {
+	metric, b := vc.breakdown(key)
+	if b == nil {
+		return key
+	}
+	dimensions := strings.Join(b.Dimensions, ",")
+	var metrics []string
+	for m, bs := range vc.Breakdowns {
+		for _, o := range bs {
+			if strings.Join(o.Dimensions, ",") == dimensions {
+				metrics = append(metrics, m)
+				break
+			}
+		}
+	}
+	if len(metrics) > maxQueryMetrics {
+		return metric
+	}
+	sort.Strings(metrics)
+	return strings.Join(metrics, ",")
+}
+
+// mapping returns how the response of a breakdown of metric becomes
+// samples: its mapping, or the default one, restricted to the column of
+// the metric and suffixed with the breakdown name.
+func (b *breakdownConf) mapping(metric string, headers []*analytics.RealtimeDataColumnHeaders) *mapping {
+	mp := defaultMapping(headers)
+	if b.Mapping != nil {
+		c := *b.Mapping
+		mp = &c
+	}
+	mp.Values = []string{metric}
+	mp.suffix = "_" + b.Name
+	return mp
+}
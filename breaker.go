@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	errBreakerOpen = errors.New("circuit breaker open, skipping query")
+
+	breakerOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ga_exporter_circuit_open",
+		Help: "Whether queries to a view are suspended after repeated failures.",
+	}, []string{"viewid", "alias"})
+)
+
+func init() {
+	prometheus.MustRegister(breakerOpen)
+}
+
+// breaker suspends queries after threshold consecutive failures. Once the
+// cooldown elapses a single probe is let through, closing the breaker on
+// success and reopening it on failure.
+type breaker struct {
+	name      string
+	alias     string
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(name, alias string, threshold int, cooldown time.Duration) *breaker {
+	breakerOpen.WithLabelValues(name, alias).Set(0)
+	return &breaker{name: name, alias: alias, threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a query may be issued, always for a nil breaker.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+
+	return true
+}
+
+// record updates the breaker with the outcome of a query.
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		breakerOpen.WithLabelValues(b.name, b.alias).Set(0)
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		breakerOpen.WithLabelValues(b.name, b.alias).Set(1)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// builder identifies the pipeline that built the binary, set at build time
+// with -ldflags "-X main.builder=...".
+var builder = "unknown"
+
+// revision is the VCS revision of builds the go tool can't stamp, e.g.
+// outside a checkout, set with -ldflags "-X main.revision=...".
+var revision string
+
+// buildInfo is the provenance of the running binary.
+type buildInfo struct {
+	Version string `json:"version"`
+	// Revision, Time and Modified are those of the VCS checkout the
+	// binary was built from, as recorded by the go tool. Revision falls
+	// back to the one set at build time.
+	Revision  string `json:"revision"`
+	Time      string `json:"time"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+	Builder   string `json:"builder"`
+}
+
+// build is read once, it doesn't change while running.
+var build = readBuildInfo()
+
+func init() {
+	info := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_build_info",
+		Help: "Provenance of the running binary, always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":   build.Version,
+			"revision":  build.Revision,
+			"modified":  strconv.FormatBool(build.Modified),
+			"goversion": build.GoVersion,
+			"builder":   build.Builder,
+		},
+	})
+	info.Set(1)
+	prometheus.MustRegister(info)
+}
+
+// readBuildInfo returns the provenance embedded in the binary.
+func readBuildInfo() buildInfo {
+	b := buildInfo{Version: version, Revision: revision, GoVersion: runtime.Version(), Builder: builder}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if s.Value != "" {
+				b.Revision = s.Value
+			}
+		case "vcs.time":
+			b.Time = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
+
+// buildInfoHandler serves the provenance of the binary as JSON.
+func buildInfoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(build)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+)
+
+var (
+	// cycleCache holds the RealTime responses of the current collection
+	// cycle.
+	cycleCache = newResponseCache()
+
+	cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_response_cache_hits_total",
+		Help: "RealTime API queries answered with the response of an identical query of the same cycle.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits)
+}
+
+// responseCache shares the response of a query between identical queries,
+// e.g. of configs generated by templating tools. Queries issued while the
+// first one is in flight wait for its response.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done chan struct{}
+	m    *analytics.RealtimeData
+	err  error
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*cacheEntry)}
+}
+
+// reset drops the cached responses, at the start of every cycle.
+func (c *responseCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+}
+
+// get returns the cached response of key, calling fetch on a miss.
+func (c *responseCache) get(key string, fetch func() (*analytics.RealtimeData, error)) (*analytics.RealtimeData, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-e.done
+		cacheHits.Inc()
+		return e.m, e.err
+	}
+	defer close(e.done)
+	e.m, e.err = fetch()
+	return e.m, e.err
+}
+
+// signature identifies a query of a view, identical queries have the same
+// signature.
+func (q *realtimeQuery) signature(viewID string) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%d|%s", viewID, q.metric, q.dimensions, q.sort, q.maxResults, q.filters)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+)
+
+// campaignConf defines the campaign attribution collector of a view.
+type campaignConf struct {
+	// Top is the number of campaign, source and medium combinations
+	// exported, by active users.
+	Top int64 `yaml:"top"`
+	// Lowercase UTM values, utm_source=Facebook and facebook are the same
+	// source.
+	Lowercase bool `yaml:"lowercase"`
+	// Aliases rename values per label, e.g. source: {fb: facebook}. They
+	// apply after lowercasing.
+	Aliases map[string]map[string]string `yaml:"aliases"`
+}
+
+// campaignLabels are the labels of the campaign vector, in the order of the
+// query dimensions.
+var campaignLabels = []string{"campaign", "source", "medium"}
+
+// campaignCollector exports the active users by campaign, source and medium
+// of every view with a campaigns section, for attribution dashboards.
+type campaignCollector struct {
+	rts *analytics.DataRealtimeService
+}
+
+// newCampaignVec returns the vector of the campaign collector of a view.
+func newCampaignVec(v *view) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_active_users_by_campaign",
+		Help:        "Active users by campaign, source and medium.",
+		ConstLabels: v.constLabels(),
+	}, campaignLabels)
+}
+
+func (c *campaignCollector) name() string { return "campaigns" }
+
+// collect queries the top campaigns of every view.
+func (c *campaignCollector) collect() error {
+	var errs []string
+	for _, v := range getViews() {
+		if v.Campaigns == nil {
+			continue
+		}
+		m, err := v.query(c.rts, &realtimeQuery{
+			metric:     "rt:activeUsers",
+			dimensions: "rt:campaign,rt:source,rt:medium",
+			sort:       "-rt:activeUsers",
+			maxResults: v.Campaigns.Top,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("view %s: %v", v.displayName(), err))
+			continue
+		}
+
+		// Normalized values can collapse, their users are summed.
+		var samples []*sample
+		seen := make(map[string]*sample)
+		for _, row := range m.Rows {
+			if len(row) < 4 {
+				continue
+			}
+			labelValues := make([]string, len(campaignLabels))
+			for i, label := range campaignLabels {
+				labelValues[i] = v.Campaigns.normalize(label, row[i])
+			}
+			key := strings.Join(labelValues, "\xff")
+			s, ok := seen[key]
+			if !ok {
+				s = &sample{labelValues: labelValues}
+				seen[key] = s
+				samples = append(samples, s)
+			}
+			valf, _ := strconv.ParseFloat(row[3], 64)
+			s.add("sum", valf)
+		}
+
+		vec := v.campaignVec
+		vec.Reset()
+		for _, s := range samples {
+			vec.WithLabelValues(s.labelValues...).Set(s.result("sum"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// normalize applies the UTM normalization rules to the value of a label.
+func (cc *campaignConf) normalize(label, value string) string {
+	value = strings.TrimSpace(sanitizeLabelValue(value))
+	if cc.Lowercase {
+		value = strings.ToLower(value)
+	}
+	if alias, ok := cc.Aliases[label][value]; ok {
+		return alias
+	}
+	return value
+}
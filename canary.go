@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/analytics/v3"
+)
+
+var (
+	canaryRuns = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_canary_cycles_total",
+		Help: "Collection cycles run for the canary config.",
+	})
+	canaryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ga_exporter_canary_errors_total",
+		Help: "Failed queries of the canary config.",
+	}, []string{"viewid", "alias"})
+	canarySeries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ga_exporter_canary_series",
+		Help: "Series the canary config would add, remove or keep compared to the running config.",
+	}, []string{"viewid", "alias", "change"})
+)
+
+func init() {
+	prometheus.MustRegister(canaryRuns, canaryErrors, canarySeries)
+}
+
+// canary runs the queries of a candidate config without exposing their
+// results, reporting errors and series differences with the running
+// config for a number of cycles.
+type canary struct {
+	views []*view
+
+	mu     sync.Mutex
+	cycles int
+}
+
+// newCanary loads the candidate config from filename.
+func newCanary(filename string, cycles int) (*canary, error) {
+	c := new(conf)
+	c.getConf(filename)
+
+	t, err := parseNameTemplate(c.MetricNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("metric_name_template: %v", err)
+	}
+
+	cn := &canary{cycles: cycles}
+	for _, vc := range c.Views {
+		cn.views = append(cn.views, newDryRunView(vc, t))
+	}
+
+	return cn, nil
+}
+
+// run performs a canary cycle, until all cycles are done.
+func (cn *canary) run(rts *analytics.DataRealtimeService) {
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+
+	if cn.cycles <= 0 {
+		return
+	}
+	cn.cycles--
+
+	// The canary runs alongside the cycles, its queries are not aborted by
+	// their timeout but by its own.
+	ctx := context.Background()
+	if config.CycleTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.CycleTimeout)*time.Second)
+		defer cancel()
+	}
+	var wg sync.WaitGroup
+	for _, v := range cn.views {
+		v.ctx = ctx
+		for _, metric := range v.queries() {
+			wg.Add(1)
+			go func(v *view, metric string) {
+				defer wg.Done()
+				if err := v.collectMetric(rts, metric, v.getDimensions(metric)); err != nil {
+					canaryErrors.WithLabelValues(v.ViewID, v.Alias).Inc()
+					log.Printf("canary: view %s: %s: %v", v.displayName(), metric, err)
+				}
+			}(v, metric)
+		}
+	}
+	wg.Wait()
+
+	for _, v := range cn.views {
+		cn.compare(v)
+	}
+	canaryRuns.Inc()
+	if cn.cycles == 0 {
+		log.Printf("canary: done")
+	}
+}
+
+// compare reports the series of a canary view missing from, or added to,
+// the running view with the same ID.
+func (cn *canary) compare(cv *view) {
+	canary, err := seriesOf(cv.registry)
+	if err != nil {
+		log.Printf("canary: view %s: %v", cv.displayName(), err)
+		return
+	}
+	running := make(map[string]bool)
+	for _, v := range getViews() {
+		if v.ViewID != cv.ViewID {
+			continue
+		}
+		if running, err = seriesOf(v.registry); err != nil {
+			log.Printf("canary: view %s: %v", cv.displayName(), err)
+			return
+		}
+	}
+
+	var added, removed []string
+	unchanged := 0
+	for s := range canary {
+		if running[s] {
+			unchanged++
+		} else {
+			added = append(added, s)
+		}
+	}
+	for s := range running {
+		if !canary[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	canarySeries.WithLabelValues(cv.ViewID, cv.Alias, "added").Set(float64(len(added)))
+	canarySeries.WithLabelValues(cv.ViewID, cv.Alias, "removed").Set(float64(len(removed)))
+	canarySeries.WithLabelValues(cv.ViewID, cv.Alias, "unchanged").Set(float64(unchanged))
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	for _, s := range added {
+		log.Printf("canary: view %s: + %s", cv.displayName(), s)
+	}
+	for _, s := range removed {
+		log.Printf("canary: view %s: - %s", cv.displayName(), s)
+	}
+}
+
+// seriesOf returns the identities, name and labels, of the gathered series.
+func seriesOf(g prometheus.Gatherer) (map[string]bool, error) {
+	mfs, err := g.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[string]bool)
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			series[seriesID(mf.GetName(), m.Label)] = true
+		}
+	}
+
+	return series, nil
+}
+
+func seriesID(name string, labels []*dto.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+	}
+	sort.Strings(pairs)
+
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// defaultCardinalityTop is the number of label values listed per label.
+const defaultCardinalityTop = 10
+
+// metricCardinality is the series count of a metric, with the values of its
+// labels that have the most series.
+type metricCardinality struct {
+	Name   string             `json:"name"`
+	Series int                `json:"series"`
+	Labels []labelCardinality `json:"labels"`
+}
+
+// labelCardinality is the number of values of a label of a metric.
+type labelCardinality struct {
+	Name   string            `json:"name"`
+	Values int               `json:"values"`
+	Top    []labelValueCount `json:"top"`
+}
+
+type labelValueCount struct {
+	Value  string `json:"value"`
+	Series int    `json:"series"`
+}
+
+// cardinalityHandler serves the series count of every metric, most series
+// first, with the top label values by series, as JSON or, with
+// ?format=text, as text. ?top= sets the number of label values listed, 10
+// by default. With ?tenant= only the views of the tenant are reported, to
+// holders of its token. Otherwise the metrics of every tenant are reported
+// with all, on the admin listener, or only those of views without tenant.
+func cardinalityHandler(all bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveCardinality(w, r, all)
+	}
+}
+
+func serveCardinality(w http.ResponseWriter, r *http.Request, all bool) {
+	var g prometheus.Gatherer = prometheus.Gatherers{prometheus.DefaultGatherer, viewsGatherer("")}
+	if name := r.URL.Query().Get("tenant"); name != "" {
+		t, ok := tenants[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown tenant %q", name), http.StatusNotFound)
+			return
+		}
+		if !t.authorize(w, r) {
+			return
+		}
+		g = viewsGatherer(name)
+	} else if all {
+		g = allGatherers()
+	}
+
+	top := defaultCardinalityTop
+	if s := r.URL.Query().Get("top"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid top %q", s), http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	// Gathering fails on inconsistent metrics, the others are reported.
+	mfs, _ := g.Gather()
+	report := cardinality(mfs, top)
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, mc := range report {
+			fmt.Fprintf(w, "%s %d\n", mc.Name, mc.Series)
+			for _, lc := range mc.Labels {
+				fmt.Fprintf(w, "  %s %d values\n", lc.Name, lc.Values)
+				for _, vc := range lc.Top {
+					fmt.Fprintf(w, "    %q %d\n", vc.Value, vc.Series)
+				}
+			}
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+}
+
+// cardinality returns the series counts of metric families, most series
+// first. Histograms and summaries count the series they are stored as, one
+// per bucket or quantile plus their sum and count.
+func cardinality(mfs []*dto.MetricFamily, top int) []metricCardinality {
+	report := make([]metricCardinality, 0, len(mfs))
+	for _, mf := range mfs {
+		mc := metricCardinality{Name: mf.GetName()}
+		values := make(map[string]map[string]int)
+		var labels []string
+		for _, m := range mf.Metric {
+			series := 1
+			switch {
+			case m.Histogram != nil:
+				// The +Inf bucket is implicit.
+				series = len(m.Histogram.Bucket) + 3
+			case m.Summary != nil:
+				series = len(m.Summary.Quantile) + 2
+			}
+			mc.Series += series
+
+			for _, lp := range m.Label {
+				name := lp.GetName()
+				if values[name] == nil {
+					values[name] = make(map[string]int)
+					labels = append(labels, name)
+				}
+				values[name][lp.GetValue()] += series
+			}
+		}
+
+		sort.Strings(labels)
+		for _, name := range labels {
+			lc := labelCardinality{Name: name, Values: len(values[name])}
+			for value, series := range values[name] {
+				lc.Top = append(lc.Top, labelValueCount{Value: value, Series: series})
+			}
+			sort.Slice(lc.Top, func(i, j int) bool {
+				if lc.Top[i].Series != lc.Top[j].Series {
+					return lc.Top[i].Series > lc.Top[j].Series
+				}
+				return lc.Top[i].Value < lc.Top[j].Value
+			})
+			if len(lc.Top) > top {
+				lc.Top = lc.Top[:top]
+			}
+			mc.Labels = append(mc.Labels, lc)
+		}
+		report = append(report, mc)
+	}
+
+	sort.SliceStable(report, func(i, j int) bool { return report[i].Series > report[j].Series })
+	return report
+}
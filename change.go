@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// lastPoll is the previous value of a series with a change rate.
+type lastPoll struct {
+	valf float64
+	t    time.Time
+}
+
+// changeRated reports whether a metric of the view has a change rate.
+func (vc *viewConf) changeRated(metric string) bool {
+	for _, m := range vc.ChangePerMinute {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// changeSuffix suffixes the name of the change rate of a metric.
+const changeSuffix = "_change_per_minute"
+
+// trackChange exports the change of a value of the series name and labels
+// of metric since the previous poll, per minute, as the metric name with a
+// _change_per_minute suffix. Unlike deriv() over the gauge, it doesn't
+// depend on how the scrapes line up with the polls. Values restored from
+// the disk cache are not polls, they don't count. Series with labels count
+// against the series limit.
+func (v *view) trackChange(metric, name string, labelNames, labelValues []string, valf float64) error {
+	if !v.changeRated(metric) || v.restoring {
+		return nil
+	}
+	now := time.Now()
+
+	v.mu.Lock()
+	key := name + "\xff" + strings.Join(labelValues, "\xff")
+	last, ok := v.lastPolls[key]
+	v.lastPolls[key] = lastPoll{valf: valf, t: now}
+	v.mu.Unlock()
+	elapsed := now.Sub(last.t).Minutes()
+	if !ok || elapsed <= 0 {
+		return nil
+	}
+	if len(labelNames) > 0 && !v.admitSeries(name+changeSuffix, labelValues) {
+		return nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	vec, ok := v.changeVec[name]
+	if !ok {
+		mname, err := v.metricName(name)
+		if err != nil {
+			return err
+		}
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        mname + changeSuffix,
+			Help:        fmt.Sprintf("Change of Google Analytics %s per minute between the last two polls", name),
+			ConstLabels: v.constLabels(),
+		}, labelNames)
+		if err := v.registry.Register(vec); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		v.changeVec[name] = vec
+	}
+	g, err := vec.GetMetricWithLabelValues(labelValues...)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	g.Set((valf - last.valf) / elapsed)
+	return nil
+}
+
+// dropChange drops the change rate of the series of name and their last
+// polls, along with the vector of name. The caller holds v.mu.
+func (v *view) dropChange(name string) {
+	if vec, ok := v.changeVec[name]; ok {
+		v.registry.Unregister(vec)
+		delete(v.changeVec, name)
+		v.limit.release(len(v.series[name+changeSuffix]))
+		delete(v.series, name+changeSuffix)
+		v.seriesCount.DeleteLabelValues(name + changeSuffix)
+	}
+	prefix := name + "\xff"
+	for key := range v.lastPolls {
+		if strings.HasPrefix(key, prefix) {
+			delete(v.lastPolls, key)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collector is an optional data source polled on every collection cycle
+// alongside the GA RealTime metrics.
+type collector interface {
+	// name identifies the collector in logs.
+	name() string
+	// collect queries the upstream API and updates the collector's metrics.
+	collect() error
+}
+
+// newGaugeVec returns a GaugeVec carrying the exporter's common labels.
+func newGaugeVec(name, help string, labels []string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        name,
+		Help:        help,
+		ConstLabels: map[string]string{"job": "googleAnalytics"},
+	}, labels)
+}
+
+// registerAll registers collector metrics, stopping at the first failure.
+func registerAll(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := prometheus.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
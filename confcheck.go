@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// confErrors lists all problems found in a configuration file.
+type confErrors []string
+
+func (e confErrors) Error() string {
+	return fmt.Sprintf("invalid configuration:\n  %s", strings.Join(e, "\n  "))
+}
+
+// check reports duplicate views and metrics, invalid schedules, bounds or
+// filters, and dimensions, mappings, schedules, bounds or anomaly scores
+// referencing metrics the view does not declare, which would otherwise only
+// surface at collection time. Filters are compiled and the metric names of
+// every view rendered.
+func (c *conf) check() error {
+	var errs confErrors
+
+	switch c.IPProtocol {
+	case "", "ipv4", "ipv6":
+	default:
+		errs = append(errs, fmt.Sprintf("ip_protocol: %q is not ipv4 or ipv6", c.IPProtocol))
+	}
+
+	if s := c.Snapshots; s != nil {
+		if s.Destination == "" {
+			errs = append(errs, "snapshots: destination is required")
+		}
+		if s.Format != "" && s.Format != "csv" && s.Format != "parquet" {
+			errs = append(errs, fmt.Sprintf("snapshots: format %q is not supported, csv or parquet", s.Format))
+		}
+		reports := make(map[string]bool)
+		for _, rc := range c.Reports {
+			reports[rc.Name] = true
+		}
+		if len(reports) == 0 {
+			errs = append(errs, "snapshots: no reports are configured")
+		}
+		for _, name := range s.Reports {
+			if !reports[name] {
+				errs = append(errs, fmt.Sprintf("snapshots: unknown report %q", name))
+			}
+		}
+	}
+
+	// A report metric is a single vector labeled by the report dimensions,
+	// the reports sharing it must have the same.
+	reportDimensions := make(map[string]*reportConf)
+	for _, rc := range c.Reports {
+		for _, m := range rc.Metrics {
+			o, ok := reportDimensions[m]
+			if !ok {
+				reportDimensions[m] = rc
+				continue
+			}
+			if strings.Join(o.Dimensions, ",") != strings.Join(rc.Dimensions, ",") {
+				errs = append(errs, fmt.Sprintf("report %s: metric %s has other dimensions than in report %s", rc.Name, m, o.Name))
+			}
+		}
+	}
+
+	if bq := c.BigQuery; bq != nil && (bq.Project == "" || bq.Dataset == "" || bq.Table == "") {
+		errs = append(errs, "bigquery: project, dataset and table are required")
+	}
+
+	if vm := c.VictoriaMetrics; vm != nil {
+		if u, err := url.Parse(vm.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("victoriametrics: invalid url %q", vm.URL))
+		}
+	}
+
+	for i, mc := range c.MaintenanceWindows {
+		if _, err := mc.compile(); err != nil {
+			errs = append(errs, fmt.Sprintf("maintenance window #%d: %v", i+1, err))
+		}
+	}
+
+	if cc := c.Consul; cc != nil && strings.Trim(cc.Prefix, "/") == "" {
+		errs = append(errs, "consul: prefix is required")
+	}
+
+	if g := c.Graphite; g != nil {
+		if _, _, err := net.SplitHostPort(g.Address); err != nil {
+			errs = append(errs, fmt.Sprintf("graphite: invalid address %q", g.Address))
+		}
+	}
+
+	if a := c.ExpositionArchive; a != nil && a.Destination == "" {
+		errs = append(errs, "exposition_archive: destination is required")
+	}
+
+	nt, err := parseNameTemplate(c.MetricNameTemplate)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("metric_name_template: %v", err))
+	}
+
+	viewIDs := make(map[string]bool)
+	for i, v := range c.Views {
+		if v.ViewID == "" {
+			errs = append(errs, fmt.Sprintf("view #%d: viewid is required", i+1))
+			continue
+		}
+		if viewIDs[v.ViewID] {
+			errs = append(errs, fmt.Sprintf("view %s: declared more than once", v.displayName()))
+		}
+		viewIDs[v.ViewID] = true
+
+		metrics := make(map[string]bool)
+		for _, m := range v.Metrics {
+			if metrics[m] {
+				errs = append(errs, fmt.Sprintf("view %s: metric %s declared more than once", v.displayName(), m))
+			}
+			metrics[m] = true
+		}
+		// The names of the metrics of the view, its alias included, must
+		// be valid.
+		if nt != nil {
+			for _, key := range v.queries() {
+				for _, m := range strings.Split(key, ",") {
+					if _, err := renderName(nt, &nameData{View: v, Metric: m}); err != nil {
+						errs = append(errs, fmt.Sprintf("view %s: name of %s: %v", v.displayName(), m, err))
+					}
+				}
+			}
+		}
+		// Breakdowns are configured like metrics, as are the metrics they
+		// break down.
+		for m, bs := range v.Breakdowns {
+			if strings.Contains(m, ",") {
+				errs = append(errs, fmt.Sprintf("view %s: breakdowns of %s: a single metric is required", v.displayName(), m))
+			}
+			for _, b := range bs {
+				key := breakdownKey(m, b.Name)
+				switch {
+				case !breakdownName.MatchString(b.Name):
+					errs = append(errs, fmt.Sprintf("view %s: breakdown of %s: invalid name %q", v.displayName(), m, b.Name))
+				case len(b.Dimensions) == 0:
+					errs = append(errs, fmt.Sprintf("view %s: breakdown %s: dimensions are required", v.displayName(), key))
+				case metrics[key]:
+					errs = append(errs, fmt.Sprintf("view %s: breakdown %s declared more than once", v.displayName(), key))
+				}
+				if b.Mapping != nil && b.Mapping.Merge != "" && !merges[b.Mapping.Merge] {
+					errs = append(errs, fmt.Sprintf("view %s: breakdown %s: unknown merge %q", v.displayName(), key, b.Mapping.Merge))
+				}
+				metrics[key] = true
+			}
+		}
+		for m := range v.Breakdowns {
+			metrics[m] = true
+		}
+
+		dimensioned := make(map[string]bool)
+		for _, dm := range v.Dimensions {
+			for m := range dm {
+				if !metrics[m] {
+					errs = append(errs, fmt.Sprintf("view %s: dimensions reference undeclared metric %s", v.displayName(), m))
+				}
+				if dimensioned[m] {
+					errs = append(errs, fmt.Sprintf("view %s: dimensions of metric %s declared more than once", v.displayName(), m))
+				}
+				dimensioned[m] = true
+			}
+		}
+
+		for m, rules := range v.Schedules {
+			if !metrics[m] {
+				errs = append(errs, fmt.Sprintf("view %s: schedule references undeclared metric %s", v.displayName(), m))
+			}
+			for _, r := range rules {
+				if err := r.check(); err != nil {
+					errs = append(errs, fmt.Sprintf("view %s: schedule of %s: %v", v.displayName(), m, err))
+				}
+			}
+		}
+
+		for m, mp := range v.Mappings {
+			if !metrics[m] {
+				errs = append(errs, fmt.Sprintf("view %s: mapping references undeclared metric %s", v.displayName(), m))
+			}
+			if mp == nil || mp.Merge == "" {
+				continue
+			}
+			if !merges[mp.Merge] {
+				errs = append(errs, fmt.Sprintf("view %s: mapping of %s: unknown merge %q", v.displayName(), m, mp.Merge))
+			}
+		}
+
+		for c, f := range v.Filters {
+			if f == nil {
+				continue
+			}
+			if err := f.compile(); err != nil {
+				errs = append(errs, fmt.Sprintf("view %s: filter of %s: %v", v.displayName(), c, err))
+			}
+		}
+
+		if r := v.Retry; r != nil && r.MaxBackoff > 0 && r.BaseBackoff > r.MaxBackoff {
+			errs = append(errs, fmt.Sprintf("view %s: retry: base_backoff %g is above max_backoff %g", v.displayName(), r.BaseBackoff, r.MaxBackoff))
+		}
+
+		for m, b := range v.Bounds {
+			if !metrics[m] {
+				errs = append(errs, fmt.Sprintf("view %s: bounds reference undeclared metric %s", v.displayName(), m))
+			}
+			if b == nil {
+				continue
+			}
+			if err := b.check(); err != nil {
+				errs = append(errs, fmt.Sprintf("view %s: bounds of %s: %v", v.displayName(), m, err))
+			}
+		}
+
+		for m, p := range v.Precision {
+			if !metrics[m] {
+				errs = append(errs, fmt.Sprintf("view %s: precision references undeclared metric %s", v.displayName(), m))
+			}
+			if p == nil {
+				continue
+			}
+			if err := p.check(); err != nil {
+				errs = append(errs, fmt.Sprintf("view %s: precision of %s: %v", v.displayName(), m, err))
+			}
+		}
+
+		if v.Anomaly != nil {
+			for _, m := range v.Anomaly.Metrics {
+				if !metrics[m] {
+					errs = append(errs, fmt.Sprintf("view %s: anomaly references undeclared metric %s", v.displayName(), m))
+				}
+			}
+		}
+		for _, m := range v.ChangePerMinute {
+			if !metrics[m] {
+				errs = append(errs, fmt.Sprintf("view %s: change_per_minute references undeclared metric %s", v.displayName(), m))
+			}
+		}
+
+		if v.Content != nil && v.Content.Merge != "" && !merges[v.Content.Merge] {
+			errs = append(errs, fmt.Sprintf("view %s: content: unknown merge %q", v.displayName(), v.Content.Merge))
+		}
+		if v.Campaigns != nil {
+			for label := range v.Campaigns.Aliases {
+				if label != "campaign" && label != "source" && label != "medium" {
+					errs = append(errs, fmt.Sprintf("view %s: campaigns: aliases of unknown label %q", v.displayName(), label))
+				}
+			}
+		}
+		if v.GoalValues != nil {
+			for _, g := range v.GoalValues.Goals {
+				if g < 1 || g > 20 {
+					errs = append(errs, fmt.Sprintf("view %s: goal_values: goal %d out of range, 1 to 20", v.displayName(), g))
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
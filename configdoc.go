@@ -0,0 +1,136 @@
+// Code generated by gen_configdoc.go; DO NOT EDIT.
+
+package main
+
+// configDocs are the doc comments of the config fields, by struct and field.
+var configDocs = map[string]string{
+	"anomalyConf.Metrics":             "Metrics scored, of the metrics of the view.",
+	"anomalyConf.MinSamples":          "MinSamples of an hour of the week before it is scored. Defaults to 30.",
+	"anomalyConf.Weeks":               "Weeks the baseline of an hour of the week is averaged over. Defaults to 4.",
+	"archiveConf.Destination":         "Destination is a gs://bucket/prefix or s3://bucket/prefix URL, or a local directory.",
+	"archiveConf.Interval":            "Interval in seconds between uploads. Defaults to an hour.",
+	"boundsConf.Max":                  "Min and Max clamp the values.",
+	"boundsConf.MaxChange":            "MaxChange in percent of the previous value, larger changes are rejected and the previous value kept.",
+	"boundsConf.MaxRejections":        "MaxRejections in a row after which a change is taken as real. Defaults to 1, a single sample spike.",
+	"boundsConf.Min":                  "Min and Max clamp the values.",
+	"breakdownConf.Dimensions":        "Dimensions the metric is broken down by.",
+	"breakdownConf.Mapping":           "Mapping of the response columns, labeled after the dimensions by default. Only the column of the metric is exported.",
+	"breakdownConf.Name":              "Name suffixes the metric name, e.g. by_country.",
+	"campaignConf.Aliases":            "Aliases rename values per label, e.g. source: {fb: facebook}. They apply after lowercasing.",
+	"campaignConf.Lowercase":          "Lowercase UTM values, utm_source=Facebook and facebook are the same source.",
+	"campaignConf.Top":                "Top is the number of campaign, source and medium combinations exported, by active users.",
+	"conf.AdminAddress":               "AdminAddress is a host:port the health, reload, profiling and config endpoints are served on, apart from the metrics.",
+	"conf.AdminToken":                 "AdminToken is required as a bearer token by the admin actions, reload, pause and resume. Without it reload is open to whoever reaches the admin listener, pause and resume are disabled.",
+	"conf.ApplicationName":            "ApplicationName identifies the exporter in the User-Agent of API calls, e.g. for Google support on quota tickets.",
+	"conf.BigQuery":                   "BigQuery streams the values collected every cycle to a table.",
+	"conf.CacheDir":                   "CacheDir keeps the last responses on disk, exported at startup until fresh data is fetched.",
+	"conf.ClientEmail":                "Alternative to the CRED_FILE json key, for secret stores providing the service account email and PEM private key separately.",
+	"conf.CompactionInterval":         "CompactionInterval in seconds, metric vectors not updated for as long are dropped. Defaults to an hour.",
+	"conf.Concurrency":                "Concurrency bounds the queries and collectors run at once.",
+	"conf.Consul":                     "Consul adds the views of a KV prefix, applied as they change.",
+	"conf.CycleTimeout":               "CycleTimeout in seconds bounds a collection cycle, the queries not done by then are cancelled and run again next cycle. No timeout by default.",
+	"conf.ErrorBufferSize":            "ErrorBufferSize is the number of collection errors served on /errors. Defaults to 100.",
+	"conf.ExpositionArchive":          "ExpositionArchive uploads the exposition periodically.",
+	"conf.FreshnessSLO":               "FreshnessSLO in seconds, metrics not updated for longer are logged and notified to FreshnessWebhook.",
+	"conf.GA4Trend":                   "GA4Trend exports the last minutes of GA4 properties per minute.",
+	"conf.Graphite":                   "Graphite receives the values of all metrics periodically.",
+	"conf.HandoffSocket":              "HandoffSocket is a Unix socket the metric values are handed off on to the next process, avoiding a gap on restarts.",
+	"conf.IPProtocol":                 "IPProtocol restricts listeners to ipv4 or ipv6.",
+	"conf.Interval":                   "Interval in seconds between polls of the metrics without a schedule and runs of the collectors. Defaults to a minute.",
+	"conf.LabelSnapshots":             "LabelSnapshots keeps the label values observed per dimension, with when they were first and last seen.",
+	"conf.ListenAddresses":            "ListenAddresses are host:port addresses the metrics are served on, instead of promport on all addresses.",
+	"conf.MaintenanceWindows":         "MaintenanceWindows are recurring windows during which nothing is collected, e.g. while GA reprocesses a property.",
+	"conf.MaxRuntime":                 "MaxRuntime in seconds after which the exporter exits, for a supervisor to restart it.",
+	"conf.MetricNameTemplate":         "MetricNameTemplate is a Go template naming the GA metrics.",
+	"conf.MetricsPath":                "MetricsPath is the path metrics are served on, / redirects to it.",
+	"conf.OutboundTLS":                "OutboundTLS sets a CA bundle and client certificate for connections to Google APIs.",
+	"conf.Plugins":                    "Plugins are external binaries contributing metrics every cycle.",
+	"conf.Proxy":                      "Proxy is the URL of the proxy to Google APIs, socks5://, http:// or https://. Defaults to the HTTPS_PROXY environment variable.",
+	"conf.Reports":                    "Reports are Reporting API queries, polled on their own interval.",
+	"conf.Scopes":                     "Scopes overrides the OAuth scopes derived from enabled collectors.",
+	"conf.ScrapeTrigger":              "ScrapeTrigger runs the collection after scrapes of the metrics, instead of every second.",
+	"conf.SearchConsole":              "Optional collectors, enabled when their section is present.",
+	"conf.SeriesLimit":                "SeriesLimit caps the label combinations exported across all views, new ones are dropped once reached.",
+	"conf.Snapshots":                  "Snapshots write the final values of every day of the reports to files.",
+	"conf.StrictMetrics":              "StrictMetrics only exports the metrics and dimensions declared in the config, e.g. no metrics named after event actions.",
+	"conf.ValidHostnames":             "ValidHostnames restricts the queries of every view to traffic of these hostnames, discarding ghost spam.",
+	"conf.VictoriaMetrics":            "VictoriaMetrics receives the values collected every cycle.",
+	"conf.Views":                      "Views lists additional GA views, each with its own metrics. The top level viewid/metrics/dimensions are kept as the first view.",
+	"consulConf.Address":              "Address of the Consul HTTP API. Defaults to the local agent.",
+	"consulConf.Prefix":               "Prefix under which every key holds a view, as a views entry of the config file in YAML, e.g. ganalytics/views/<viewid>.",
+	"contentConf.Merge":               "Merge combines pages whose titles are the same once truncated, sum, max or avg. Defaults to sum.",
+	"contentConf.TitleMaxLength":      "TitleMaxLength truncates page titles, in characters.",
+	"contentConf.Top":                 "Top is the number of pages exported, by active users.",
+	"ga4TrendConf.Metrics":            "Metrics exported per minute. Defaults to screenPageViews.",
+	"ga4TrendConf.Minutes":            "Minutes of the trend, up to 30, or 60 for Analytics 360 properties. Defaults to 30.",
+	"ga4TrendConf.Properties":         "Properties are GA4 property IDs.",
+	"goalValueConf.Goals":             "Goals are the goal numbers exported, 1 to 20.",
+	"goalValueConf.Names":             "Names of the goals, by number, exported as the goal label. Goals without name are named as in the view settings.",
+	"goalValueConf.Scale":             "Scale multiplies the values, which are in the currency of the view, e.g. to convert them to a common currency. Defaults to 1.",
+	"graphiteConf.Address":            "Address is the host:port of the plaintext listener, e.g. graphite:2003.",
+	"graphiteConf.Interval":           "Interval in seconds between flushes. Defaults to a minute.",
+	"graphiteConf.Prefix":             "Prefix of the metric paths, e.g. ga.prod.",
+	"graphiteConf.Tags":               "Tags sends the labels as Graphite tags, name;label=value, instead of path nodes, name.label.value.",
+	"labelSnapshotConf.Interval":      "Interval in seconds between writes. Defaults to an hour.",
+	"labelSnapshotConf.Path":          "Path of the JSON file the values are written to every interval and read back at startup. Without it they are only served on /labels.",
+	"labelSnapshotConf.Retention":     "Retention in seconds of values no longer seen. Defaults to a week.",
+	"maintenanceConf.Cron":            "Cron is a 5 field cron expression, minute hour day-of-month month day-of-week, e.g. \"0 2 * * sun\". Unlike cron, a window starts when both the day of the month and of the week match.",
+	"maintenanceConf.Duration":        "Duration of the window in seconds.",
+	"maintenanceConf.RRule":           "RRule is an RFC 5545 recurrence rule with a DAILY, WEEKLY or MONTHLY frequency and BYMONTHDAY, BYDAY, BYHOUR and BYMINUTE parts, e.g. \"FREQ=WEEKLY;BYDAY=SU;BYHOUR=2\".",
+	"maintenanceConf.Timezone":        "Timezone of the start, e.g. Europe/Paris. Defaults to the local time zone.",
+	"mapping.FoldCase":                "FoldCase lowercases label values, GA sometimes returns the same page path with different capitalization.",
+	"mapping.Ignore":                  "Ignore lists columns dropped from the samples.",
+	"mapping.Labels":                  "Labels maps dimension columns to the label names they become.",
+	"mapping.Merge":                   "Merge combines the values of rows ending up with the same labels, sum, max or avg. Defaults to sum.",
+	"mapping.NameFrom":                "NameFrom is a column whose value names the metric, as done for event actions without a mapping.",
+	"mapping.Values":                  "Values lists the metric columns exported, all of them when empty.",
+	"outboundTLSConf.CAFile":          "CAFile is a PEM bundle of CAs trusted in addition to the system ones.",
+	"outboundTLSConf.CertFile":        "CertFile and KeyFile are a PEM client certificate and its key.",
+	"outboundTLSConf.KeyFile":         "CertFile and KeyFile are a PEM client certificate and its key.",
+	"precisionConf.Decimals":          "Decimals rounds to a number of decimal places.",
+	"precisionConf.Significant":       "Significant rounds to a number of significant figures.",
+	"reportConf.Interval":             "Interval in seconds between queries, reports change slowly and cost more quota than RealTime queries.",
+	"reportConf.UTCDates":             "UTCDates computes the relative dates, today, yesterday and NdaysAgo, in UTC instead of the time zone of the view.",
+	"resolverConf.Network":            "Network is udp or tcp, udp by default.",
+	"resolverConf.Servers":            "Servers are host:port addresses, tried in turn.",
+	"retryConf.BaseBackoff":           "BaseBackoff and MaxBackoff in seconds bound the exponential backoff between attempts, which is jittered. Default to 1 and 30.",
+	"retryConf.MaxAttempts":           "MaxAttempts of a query, including the first. Defaults to 1.",
+	"retryConf.MaxBackoff":            "BaseBackoff and MaxBackoff in seconds bound the exponential backoff between attempts, which is jittered. Default to 1 and 30.",
+	"retryConf.RetryableCodes":        "RetryableCodes are the HTTP status codes retried, network errors are retried too. Default to 429, 500, 502, 503 and 504.",
+	"scheduleRule.Days":               "Days are weekday abbreviations, e.g. [mon, tue], every day if empty.",
+	"scheduleRule.Hours":              "Hours is a range of hours of the day, 0 to 23, e.g. \"9-18\" from 9:00 to 17:59 or \"18-0\" to midnight, the whole day if empty.",
+	"scheduleRule.Interval":           "Interval in seconds between queries.",
+	"scrapeTriggerConf.Debounce":      "Debounce in seconds, scrapes closer to the last one that triggered collection, e.g. of a second Prometheus, don't trigger it. Queries due within as long run early. Defaults to 5.",
+	"snapshotConf.Days":               "Days in the past a snapshot is still written for, once its data is golden. Defaults to 3.",
+	"snapshotConf.Destination":        "Destination is a gs://bucket/prefix or s3://bucket/prefix URL, or a local directory.",
+	"snapshotConf.Format":             "Format of the files, csv or parquet. Defaults to csv.",
+	"snapshotConf.Reports":            "Reports snapshotted, by name, all of them when empty.",
+	"valueFilter.Exclude":             "Exclude drops rows matching any of the expressions.",
+	"valueFilter.Include":             "Include keeps only rows matching any of the expressions.",
+	"victoriaMetricsConf.BearerToken": "Username and Password authenticate with basic auth, BearerToken with a bearer token.",
+	"victoriaMetricsConf.ExtraLabels": "ExtraLabels are added to every series, e.g. the environment.",
+	"victoriaMetricsConf.Password":    "Username and Password authenticate with basic auth, BearerToken with a bearer token.",
+	"victoriaMetricsConf.URL":         "URL of the JSON line import endpoint, e.g. http://victoriametrics:8428/api/v1/import.",
+	"victoriaMetricsConf.Username":    "Username and Password authenticate with basic auth, BearerToken with a bearer token.",
+	"viewConf.Alias":                  "Alias is a human-friendly name of the view, shown with its ID in logs and errors, and labelling the exporter's own metrics of the view.",
+	"viewConf.Anomaly":                "Anomaly exports scores of metrics against their usual value for the hour of the week.",
+	"viewConf.Bounds":                 "Bounds clamp or reject implausible values, per metric.",
+	"viewConf.Breakdowns":             "Breakdowns export metrics by other dimensions, each under its own name.",
+	"viewConf.BreakerCooldown":        "BreakerThreshold consecutive failures stop queries to the view for BreakerCooldown seconds.",
+	"viewConf.BreakerThreshold":       "BreakerThreshold consecutive failures stop queries to the view for BreakerCooldown seconds.",
+	"viewConf.Campaigns":              "Campaigns enables the campaign attribution collector for the view.",
+	"viewConf.ChangePerMinute":        "ChangePerMinute exports the change of metrics between consecutive polls, per minute, to alert on sudden traffic drops.",
+	"viewConf.Content":                "Content enables the active users by page collector for the view.",
+	"viewConf.DeviceSplit":            "DeviceSplit enables the active users by device category collector for the view, with their ratios.",
+	"viewConf.ExpectedValues":         "ExpectedValues lists, per dimension column, label values exported as 0 when missing from a response.",
+	"viewConf.Filters":                "Filters keep or drop rows by dimension value, after the query.",
+	"viewConf.GoalValues":             "GoalValues enables the realtime goal value collector for the view.",
+	"viewConf.IncludeNotSet":          "IncludeNotSet exports \"(not set)\" rows, which are dropped by default.",
+	"viewConf.Mappings":               "Mappings declares, per metric, how response columns become samples.",
+	"viewConf.Precision":              "Precision rounds the values, per metric.",
+	"viewConf.RateLimit":              "RateLimit caps API requests per second issued for the view.",
+	"viewConf.ReportDropped":          "ReportDropped exports the value of dropped rows per metric, so the breakdown can be reconciled with the total.",
+	"viewConf.Retry":                  "Retry is the policy of failed queries, not retried by default.",
+	"viewConf.Schedules":              "Schedules override the polling interval of metrics by time of day.",
+	"viewConf.ValidHostnames":         "ValidHostnames restricts queries to traffic of these hostnames, discarding ghost spam. Defaults to the global valid_hostnames.",
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	configHash = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ga_exporter_config_hash",
+		Help: "Hash of the loaded configuration file, always 1.",
+	}, []string{"hash"})
+	configLastReload = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration load.",
+	})
+	configLastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_config_last_reload_successful",
+		Help: "Whether the last configuration load was successful.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(configHash, configLastReload, configLastReloadSuccessful)
+}
+
+// configHashOf returns the hash of configuration file data.
+func configHashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// configLoaded records a successful load of the configuration.
+func configLoaded(hash string) {
+	configHash.Reset()
+	configHash.WithLabelValues(hash).Set(1)
+	configLastReload.Set(float64(time.Now().Unix()))
+	configLastReloadSuccessful.Set(1)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// defaultConsulAddress is the local Consul agent.
+	defaultConsulAddress = "http://127.0.0.1:8500"
+	// consulRetryDelay is the delay before querying Consul again after an
+	// error, or between polls.
+	consulRetryDelay = 10 * time.Second
+)
+
+// consulConf defines the Consul KV prefix views are read from, in addition
+// to the views of the config file.
+type consulConf struct {
+	// Address of the Consul HTTP API. Defaults to the local agent.
+	Address string `yaml:"address"`
+	// Prefix under which every key holds a view, as a views entry of the
+	// config file in YAML, e.g. ganalytics/views/<viewid>.
+	Prefix     string `yaml:"prefix"`
+	Datacenter string `yaml:"datacenter"`
+	Token      string `yaml:"token"`
+}
+
+var (
+	consulInvalidKeys = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_consul_invalid_keys",
+		Help: "Keys under the Consul prefix skipped by the last reload because they don't hold a valid view.",
+	})
+
+	// consulViewsMu guards consulKV.
+	consulViewsMu sync.Mutex
+	// consulKV are the views read from Consul, by key. They are decoded on
+	// every reload, views are modified when their defaults are set.
+	consulKV map[string][]byte
+)
+
+func init() {
+	prometheus.MustRegister(consulInvalidKeys)
+}
+
+// consulViews returns the views read from Consul to add to those of c,
+// sorted by key. Keys that don't hold a valid view, or a view already
+// declared, are logged and skipped: a bad key must not block the reloads
+// of the config file.
+func consulViews(c *conf) []*viewConf {
+	consulViewsMu.Lock()
+	defer consulViewsMu.Unlock()
+
+	keys := make([]string, 0, len(consulKV))
+	for key := range consulKV {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	declared := make(map[string]bool)
+	for _, vc := range c.Views {
+		declared[vc.ViewID] = true
+	}
+	var vcs []*viewConf
+	invalid := 0
+	for _, key := range keys {
+		vc := new(viewConf)
+		err := yaml.UnmarshalStrict(consulKV[key], vc)
+		if err == nil {
+			err = (&conf{MetricNameTemplate: c.MetricNameTemplate, Views: []*viewConf{vc}}).check()
+		}
+		if err == nil && declared[vc.ViewID] {
+			err = fmt.Errorf("view %s declared more than once", vc.displayName())
+		}
+		if err != nil {
+			log.Printf("consul: %s skipped: %v", key, err)
+			invalid++
+			continue
+		}
+		declared[vc.ViewID] = true
+		vcs = append(vcs, vc)
+	}
+	consulInvalidKeys.Set(float64(invalid))
+	return vcs
+}
+
+// consulWatcher watches the Consul prefix with blocking queries and reloads
+// the config whenever its keys change.
+type consulWatcher struct {
+	conf   *consulConf
+	client *http.Client
+}
+
+func newConsulWatcher(cc *consulConf) *consulWatcher {
+	// Blocking queries wait up to 5 minutes, plus a jitter.
+	return &consulWatcher{conf: cc, client: &http.Client{Timeout: 6 * time.Minute}}
+}
+
+// run applies the views of the prefix and every change of them, it never
+// returns.
+func (w *consulWatcher) run() {
+	var index uint64
+	var last map[string][]byte
+	for {
+		kv, next, err := w.list(index)
+		if err != nil {
+			log.Printf("consul: %v", err)
+			time.Sleep(consulRetryDelay)
+			continue
+		}
+		switch {
+		case next == 0:
+			// Without index, e.g. through a proxy, queries don't block:
+			// the keys are polled.
+			if last != nil && reflect.DeepEqual(kv, last) {
+				time.Sleep(consulRetryDelay)
+				continue
+			}
+		case next < index:
+			// The index is reset when Consul restores a snapshot.
+			index = 0
+			continue
+		case next == index:
+			continue
+		}
+		index = next
+		last = kv
+
+		consulViewsMu.Lock()
+		consulKV = kv
+		consulViewsMu.Unlock()
+		log.Printf("consul: %d views under %s", len(kv), w.conf.Prefix)
+		if err := reload(); err != nil {
+			log.Printf("config reload: %v", err)
+		}
+	}
+}
+
+// list returns the keys of the prefix once their index is past index, with
+// their new index.
+func (w *consulWatcher) list(index uint64) (map[string][]byte, uint64, error) {
+	q := url.Values{"recurse": {"true"}, "index": {strconv.FormatUint(index, 10)}, "wait": {"5m"}}
+	if w.conf.Datacenter != "" {
+		q.Set("dc", w.conf.Datacenter)
+	}
+	u := strings.TrimSuffix(w.conf.Address, "/") + "/v1/kv/" + strings.Trim(w.conf.Prefix, "/") + "?" + q.Encode()
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if w.conf.Token != "" {
+		req.Header.Set("X-Consul-Token", w.conf.Token)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	kv := make(map[string][]byte)
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		// No key under the prefix.
+		return kv, next, nil
+	case http.StatusOK:
+	default:
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var entries []struct {
+		Key   string
+		Value []byte
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, err
+	}
+	for _, e := range entries {
+		// Folders have no value.
+		if len(e.Value) > 0 {
+			kv[e.Key] = e.Value
+		}
+	}
+	return kv, next, nil
+}
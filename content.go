@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+)
+
+// contentConf defines the content collector of a view.
+type contentConf struct {
+	// Top is the number of pages exported, by active users.
+	Top int64 `yaml:"top"`
+	// TitleMaxLength truncates page titles, in characters.
+	TitleMaxLength int `yaml:"title_max_length"`
+	// Merge combines pages whose titles are the same once truncated, sum,
+	// max or avg. Defaults to sum.
+	Merge string `yaml:"merge"`
+}
+
+// contentCollector exports the active users of the top pages of every view
+// with a content section, for content dashboards.
+type contentCollector struct {
+	rts *analytics.DataRealtimeService
+}
+
+// newContentVec returns the vector of the content collector of a view.
+func newContentVec(v *view) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_active_users_by_page",
+		Help:        "Active users of the top pages by active users.",
+		ConstLabels: v.constLabels(),
+	}, []string{"path", "title"})
+}
+
+func (c *contentCollector) name() string { return "content" }
+
+// collect queries the top pages of every view.
+func (c *contentCollector) collect() error {
+	var errs []string
+	for _, v := range getViews() {
+		if v.Content == nil {
+			continue
+		}
+		m, err := v.query(c.rts, &realtimeQuery{
+			metric:     "rt:activeUsers",
+			dimensions: "rt:pagePath,rt:pageTitle",
+			sort:       "-rt:activeUsers",
+			maxResults: v.Content.Top,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("view %s: %v", v.displayName(), err))
+			continue
+		}
+
+		var samples []*sample
+		seen := make(map[string]*sample)
+		for _, row := range m.Rows {
+			if len(row) < 3 {
+				continue
+			}
+			labelValues := []string{row[0], sanitizeTitle(row[1], v.Content.TitleMaxLength)}
+			key := strings.Join(labelValues, "\xff")
+			s, ok := seen[key]
+			if !ok {
+				s = &sample{labelValues: labelValues}
+				seen[key] = s
+				samples = append(samples, s)
+			}
+			valf, _ := strconv.ParseFloat(row[2], 64)
+			s.add(v.Content.Merge, valf)
+		}
+
+		vec := v.contentVec
+		vec.Reset()
+		for _, s := range samples {
+			vec.WithLabelValues(s.labelValues...).Set(s.result(v.Content.Merge))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// sanitizeTitle drops invalid UTF-8 and control characters, collapses
+// whitespace and truncates a page title to max characters.
+func sanitizeTitle(title string, max int) string {
+	var b []rune
+	space := false
+	for _, r := range title {
+		if r == utf8.RuneError || (unicode.IsControl(r) && !unicode.IsSpace(r)) {
+			continue
+		}
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space && len(b) > 0 {
+			b = append(b, ' ')
+		}
+		space = false
+		b = append(b, r)
+	}
+
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
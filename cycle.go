@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultConcurrency bounds the queries and collectors run at once.
+const defaultConcurrency = 10
+
+var (
+	cycleTasks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_cycle_tasks",
+		Help: "Metric queries and collectors run by the last collection cycle.",
+	})
+	cycleFailures = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_cycle_failures",
+		Help: "Metric queries and collectors that failed in the last collection cycle.",
+	})
+	cycleDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_cycle_duration_seconds",
+		Help: "Duration of the last collection cycle.",
+	})
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_scheduler_queue_depth",
+		Help: "Metric queries and collectors of the running cycle waiting for a concurrency slot.",
+	})
+	cycleCancelled = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_cycle_cancelled_tasks_total",
+		Help: "Metric queries and collectors cancelled by the cycle timeout.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(cycleTasks, cycleFailures, cycleDuration, queueDepth, cycleCancelled)
+}
+
+// currentCycle holds the correlation ID of the running collection cycle,
+// currentCycleCtx a cycleCtx of its context.
+var currentCycle, currentCycleCtx atomic.Value
+
+// cycleCtx wraps the context of a cycle, an atomic.Value only holds a
+// single concrete type.
+type cycleCtx struct {
+	context.Context
+}
+
+// cycleID returns the correlation ID of the running collection cycle, found
+// in logs, audit entries and /debug/ga so a sample can be traced back to the
+// response it came from. "" before the first cycle.
+func cycleID() string {
+	id, _ := currentCycle.Load().(string)
+	return id
+}
+
+// cycleContext returns the context of the running collection cycle, done
+// once its timeout is reached. Queries issued outside of a cycle get the
+// context of the last one.
+func cycleContext() context.Context {
+	if c, ok := currentCycleCtx.Load().(cycleCtx); ok {
+		return c.Context
+	}
+	return context.Background()
+}
+
+// newCycleID returns a random correlation ID.
+func newCycleID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// task is a unit of work of a collection cycle.
+type task struct {
+	name string
+	run  func() error
+	// cancelled, when set, is called if the cycle timeout cancels the
+	// task, e.g. to run it again next cycle.
+	cancelled func()
+}
+
+// runCycle runs tasks in parallel, at most concurrency at once, and waits
+// for all of them. A failed or panicking task is logged and counted, the
+// results of the others are published regardless. Identical queries of the
+// cycle share their response. The cycle gets a new correlation ID.
+//
+// With a timeout, tasks not started by then are skipped and the queries in
+// flight are aborted; both are counted as cancelled. Collectors of other
+// APIs finish the request they are on.
+func runCycle(tasks []task, concurrency int, timeout time.Duration) {
+	if len(tasks) == 0 {
+		return
+	}
+	start := time.Now()
+	cycleCache.reset()
+	id := newCycleID()
+	currentCycle.Store(id)
+	// The context is only cancelled by the timeout.
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		timer := time.AfterFunc(timeout, cancel)
+		defer timer.Stop()
+	}
+	currentCycleCtx.Store(cycleCtx{ctx})
+
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	var mu sync.Mutex
+	failures, cancelled := 0, 0
+	queueDepth.Set(float64(len(tasks)))
+	for _, t := range tasks {
+		t := t
+		g.Go(func() (err error) {
+			queueDepth.Dec()
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic: %v", r)
+				}
+				if err == nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if ctx.Err() != nil {
+					cancelled++
+					if t.cancelled != nil {
+						t.cancelled()
+					}
+					return
+				}
+				log.Printf("cycle %s: %s: %v", id, t.name, err)
+				recentErrors.record(t.name, err)
+				failures++
+			}()
+			// Tasks not started by the timeout are skipped.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return t.run()
+		})
+	}
+	// Every error is already logged, only the count is of interest.
+	g.Wait()
+
+	if cancelled > 0 {
+		log.Printf("cycle %s: timeout of %s reached, %d of %d tasks cancelled", id, timeout, cancelled, len(tasks))
+		cycleCancelled.Add(float64(cancelled))
+	}
+	cycleTasks.Set(float64(len(tasks)))
+	cycleFailures.Set(float64(failures))
+	cycleDuration.Set(time.Since(start).Seconds())
+}
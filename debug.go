@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/api/analytics/v3"
+)
+
+// debugResponse is the last response of a RealTime query, with the cycle
+// it was fetched in.
+type debugResponse struct {
+	ViewID     string                  `json:"viewid"`
+	Metric     string                  `json:"metric"`
+	Dimensions string                  `json:"dimensions,omitempty"`
+	Cycle      string                  `json:"cycle"`
+	Fetched    time.Time               `json:"fetched"`
+	Error      string                  `json:"error,omitempty"`
+	Response   *analytics.RealtimeData `json:"response,omitempty"`
+}
+
+// lastResponses keeps the last response of every query signature, served on
+// /debug/ga.
+var lastResponses = struct {
+	sync.Mutex
+	m map[string]*debugResponse
+}{m: make(map[string]*debugResponse)}
+
+// recordResponse keeps the response of a query of a view.
+func recordResponse(viewID string, q *realtimeQuery, m *analytics.RealtimeData, err error) {
+	r := &debugResponse{
+		ViewID:     viewID,
+		Metric:     q.metric,
+		Dimensions: q.dimensions,
+		Cycle:      cycleID(),
+		Fetched:    time.Now().UTC(),
+		Response:   m,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	lastResponses.Lock()
+	defer lastResponses.Unlock()
+	lastResponses.m[q.signature(viewID)] = r
+}
+
+// debugGAHandler serves the last responses as JSON, by view and metric.
+func debugGAHandler(w http.ResponseWriter, r *http.Request) {
+	lastResponses.Lock()
+	rs := make([]*debugResponse, 0, len(lastResponses.m))
+	for _, resp := range lastResponses.m {
+		rs = append(rs, resp)
+	}
+	lastResponses.Unlock()
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].ViewID != rs[j].ViewID {
+			return rs[i].ViewID < rs[j].ViewID
+		}
+		return rs[i].Metric+rs[i].Dimensions < rs[j].Metric+rs[j].Dimensions
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(rs)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+)
+
+// deviceCategories are always exported, as 0 when GA returns no row.
+var deviceCategories = []string{"desktop", "mobile", "tablet"}
+
+// deviceCollector exports the active users by device category of every view
+// with device_split, with their share of the active users.
+type deviceCollector struct {
+	rts *analytics.DataRealtimeService
+}
+
+func (c *deviceCollector) name() string { return "device_split" }
+
+// collect queries the device split of every view.
+func (c *deviceCollector) collect() error {
+	var errs []string
+	for _, v := range getViews() {
+		if !v.DeviceSplit {
+			continue
+		}
+		m, err := v.query(c.rts, &realtimeQuery{
+			metric:     "rt:activeUsers",
+			dimensions: "rt:deviceCategory",
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("view %s: %v", v.displayName(), err))
+			continue
+		}
+
+		users := make(map[string]float64)
+		for _, d := range deviceCategories {
+			users[d] = 0
+		}
+		for _, row := range m.Rows {
+			if len(row) < 2 {
+				continue
+			}
+			valf, _ := strconv.ParseFloat(row[1], 64)
+			users[strings.ToLower(sanitizeLabelValue(row[0]))] += valf
+		}
+		v.deviceSplit.set(users)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// deviceSplit exposes the active users by device and their ratios from the
+// same response, a scrape never sees counts and ratios of different
+// responses.
+type deviceSplit struct {
+	users, ratio *prometheus.Desc
+
+	mu    sync.Mutex
+	split map[string]float64
+}
+
+func newDeviceSplit(v *view) *deviceSplit {
+	return &deviceSplit{
+		users: prometheus.NewDesc("ga_active_users_by_device",
+			"Active users by device category.", []string{"device"}, v.constLabels()),
+		ratio: prometheus.NewDesc("ga_active_users_device_ratio",
+			"Share of the active users by device category.", []string{"device"}, v.constLabels()),
+	}
+}
+
+// set replaces the active users by device.
+func (d *deviceSplit) set(split map[string]float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.split = split
+}
+
+// Describe implements prometheus.Collector.
+func (d *deviceSplit) Describe(ch chan<- *prometheus.Desc) {
+	ch <- d.users
+	ch <- d.ratio
+}
+
+// Collect implements prometheus.Collector.
+func (d *deviceSplit) Collect(ch chan<- prometheus.Metric) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	devices := make([]string, 0, len(d.split))
+	total := 0.0
+	for device, users := range d.split {
+		devices = append(devices, device)
+		total += users
+	}
+	sort.Strings(devices)
+	for _, device := range devices {
+		users := d.split[device]
+		ratio := 0.0
+		if total > 0 {
+			ratio = users / total
+		}
+		ch <- prometheus.MustNewConstMetric(d.users, prometheus.GaugeValue, users, device)
+		ch <- prometheus.MustNewConstMetric(d.ratio, prometheus.GaugeValue, ratio, device)
+	}
+}
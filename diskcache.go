@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/analytics/v3"
+)
+
+// responseDiskCache is set from cache_dir.
+var responseDiskCache *diskCache
+
+// diskCache keeps the last successful response of every metric query on
+// disk, they are exported at startup until fresh data is fetched so a
+// crash-loop doesn't blank dashboards.
+type diskCache struct {
+	dir string
+}
+
+// diskCacheEntry is a cached response with the time it was fetched.
+type diskCacheEntry struct {
+	Fetched  time.Time               `json:"fetched"`
+	Response *analytics.RealtimeData `json:"response"`
+}
+
+func newDiskCache(dir string) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: dir}, nil
+}
+
+// path returns the file of a query signature.
+func (c *diskCache) path(signature string) string {
+	sum := sha1.Sum([]byte(signature))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// store writes a response, replacing the previous one atomically. A nil
+// diskCache stores nothing.
+func (c *diskCache) store(signature string, m *analytics.RealtimeData) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(diskCacheEntry{Fetched: time.Now(), Response: m})
+	if err != nil {
+		log.Printf("disk cache: %v", err)
+		return
+	}
+	path := c.path(signature)
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		log.Printf("disk cache: %v", err)
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		log.Printf("disk cache: %v", err)
+	}
+}
+
+// load reads the cached response of a query signature, nil when none.
+func (c *diskCache) load(signature string) (*diskCacheEntry, error) {
+	data, err := ioutil.ReadFile(c.path(signature))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e diskCacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// restore exports the cached responses of the view metrics, flagged as
+// stale until they are fetched again. A nil diskCache restores nothing.
+func (c *diskCache) restore(v *view) {
+	if c == nil {
+		return
+	}
+	v.restoring = true
+	defer func() { v.restoring = false }()
+	for _, metric := range v.queries() {
+		q := &realtimeQuery{metric: v.queryMetrics(metric), dimensions: v.getDimensions(metric), filters: v.hostnameFilter()}
+		e, err := c.load(q.signature(v.ViewID))
+		if err != nil {
+			log.Printf("disk cache: view %s: %s: %v", v.displayName(), metric, err)
+			continue
+		}
+		if e == nil || e.Response == nil {
+			continue
+		}
+		if err := v.export(metric, e.Response); err != nil {
+			log.Printf("disk cache: view %s: %s: %v", v.displayName(), metric, err)
+			continue
+		}
+		v.markFreshAt(metric, e.Fetched)
+		v.stale.WithLabelValues(metric).Set(1)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultErrorBufferSize is the number of collection errors kept.
+const defaultErrorBufferSize = 100
+
+// collectionError is a failed query or collector of a cycle.
+type collectionError struct {
+	Time     time.Time `json:"time"`
+	Cycle    string    `json:"cycle"`
+	Task     string    `json:"task"`
+	Category string    `json:"category,omitempty"`
+	Error    string    `json:"error"`
+}
+
+// errorRing keeps the last collection errors, served on /errors so on-call
+// engineers see why data stopped without searching the logs. Errors are
+// recorded asynchronously, collection never waits on readers.
+type errorRing struct {
+	ch chan collectionError
+
+	mu     sync.Mutex
+	errors []collectionError
+	next   int
+	full   bool
+}
+
+// recentErrors is set in init from error_buffer_size.
+var recentErrors *errorRing
+
+func newErrorRing(size int) *errorRing {
+	r := &errorRing{ch: make(chan collectionError, size), errors: make([]collectionError, size)}
+	go r.run()
+	return r
+}
+
+// run appends the recorded errors to the ring.
+func (r *errorRing) run() {
+	for e := range r.ch {
+		r.mu.Lock()
+		r.errors[r.next] = e
+		r.next = (r.next + 1) % len(r.errors)
+		r.full = r.full || r.next == 0
+		r.mu.Unlock()
+	}
+}
+
+// record adds the error of a task, dropped when errors come in faster than
+// they are recorded. A nil errorRing records nothing.
+func (r *errorRing) record(task string, err error) {
+	if r == nil {
+		return
+	}
+	e := collectionError{Time: time.Now().UTC(), Cycle: cycleID(), Task: task, Error: err.Error()}
+	if ce, ok := err.(*categorizedError); ok {
+		e.Category, e.Error = ce.category, ce.err.Error()
+	}
+	select {
+	case r.ch <- e:
+	default:
+	}
+}
+
+// last returns the errors kept, most recent first.
+func (r *errorRing) last() []collectionError {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.errors)
+	}
+	out := make([]collectionError, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.errors[(r.next-i+len(r.errors))%len(r.errors)])
+	}
+	return out
+}
+
+// errorsHandler serves the last collection errors as JSON.
+func errorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(recentErrors.last())
+}
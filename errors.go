@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// Error categories, telling configuration problems from Google being down.
+const (
+	errAuth        = "auth"
+	errQuota       = "quota"
+	errInvalid     = "invalid_combination"
+	errNotFound    = "view_not_found"
+	errNetwork     = "network"
+	errParse       = "parse"
+	errBreaker     = "breaker_open"
+	errUnavailable = "unavailable"
+	errOther       = "other"
+)
+
+var apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "ga_exporter_errors_total",
+	Help: "Failed GA API queries by error category.",
+}, []string{"category", "viewid", "alias"})
+
+func init() {
+	prometheus.MustRegister(apiErrors)
+}
+
+// categorizedError is an API error with its category, which prefixes the
+// message as a log field.
+type categorizedError struct {
+	category string
+	err      error
+}
+
+func (e *categorizedError) Error() string {
+	return fmt.Sprintf("category=%s: %v", e.category, e.err)
+}
+
+// categorize counts a failed query of a view, with its alias if any, and
+// returns the error with its category.
+func categorize(viewID, alias string, err error) error {
+	if err == nil {
+		return nil
+	}
+	category := errorCategory(err)
+	apiErrors.WithLabelValues(category, viewID, alias).Inc()
+	return &categorizedError{category: category, err: err}
+}
+
+// errorCategory classifies an API error.
+func errorCategory(err error) string {
+	if err == errBreakerOpen {
+		return errBreaker
+	}
+	if uerr, ok := err.(*url.Error); ok {
+		// Token errors are wrapped by the HTTP client.
+		if _, ok := uerr.Err.(*oauth2.RetrieveError); ok {
+			return errAuth
+		}
+		err = uerr.Err
+	}
+
+	switch e := err.(type) {
+	case *googleapi.Error:
+		for _, item := range e.Errors {
+			switch item.Reason {
+			case "dailyLimitExceeded", "userRateLimitExceeded", "rateLimitExceeded", "quotaExceeded":
+				return errQuota
+			}
+		}
+		switch {
+		case e.Code == 429:
+			return errQuota
+		case e.Code == 401 || e.Code == 403:
+			return errAuth
+		case e.Code == 404:
+			return errNotFound
+		case e.Code == 400:
+			return errInvalid
+		case e.Code >= 500:
+			return errUnavailable
+		}
+	case *oauth2.RetrieveError:
+		return errAuth
+	case net.Error:
+		return errNetwork
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return errParse
+	}
+	return errOther
+}
@@ -0,0 +1,261 @@
+package main
+
+//go:generate go run gen_configdoc.go
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// explain prints every config key, or those under the key given, with its
+// type, default, doc comment and an example of where it goes. It returns
+// the exit status.
+func explain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ganalytics explain [key], e.g. views[].retry")
+	}
+	fs.Parse(args)
+
+	// All sections are set, so their defaults are.
+	c := new(conf)
+	fillSections(reflect.ValueOf(c).Elem())
+	c.setDefaults()
+
+	found := false
+	walkConf("", reflect.TypeOf(conf{}), reflect.ValueOf(c).Elem(), func(k confKey) {
+		if fs.NArg() > 0 && k.path != fs.Arg(0) && !strings.HasPrefix(k.path, fs.Arg(0)+".") && !strings.HasPrefix(k.path, fs.Arg(0)+"[]") {
+			return
+		}
+		found = true
+		k.print(os.Stdout)
+	})
+	if !found {
+		fmt.Fprintf(os.Stderr, "explain: unknown key %q\n", fs.Arg(0))
+		return 1
+	}
+	return 0
+}
+
+// confKey is a key of the config, with its default.
+type confKey struct {
+	path  string
+	typ   reflect.Type
+	value reflect.Value
+	doc   string
+}
+
+// print writes the key, its type and default, doc and example.
+func (k confKey) print(w io.Writer) {
+	fmt.Fprintf(w, "%s  %s", k.path, typeName(k.typ))
+	if d := k.defaultValue(); d != "" {
+		fmt.Fprintf(w, "  default: %s", d)
+	}
+	fmt.Fprintln(w)
+	if k.doc != "" {
+		fmt.Fprintf(w, "    %s\n", k.doc)
+	}
+	if !isSection(k.typ) {
+		for _, line := range strings.Split(k.example(), "\n") {
+			fmt.Fprintf(w, "    | %s\n", line)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// defaultValue returns the default of the key in YAML, lists in flow
+// style, empty when none.
+func (k confKey) defaultValue() string {
+	if isSection(k.typ) || !k.value.IsValid() || k.value.IsZero() {
+		return ""
+	}
+	switch k.typ.Kind() {
+	case reflect.Slice:
+		if isSection(k.typ.Elem()) {
+			return ""
+		}
+		items := make([]string, k.value.Len())
+		for i := range items {
+			items[i] = yamlValue(k.value.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		return ""
+	}
+	return yamlValue(k.value)
+}
+
+// yamlValue returns a scalar value in YAML.
+func yamlValue(v reflect.Value) string {
+	data, err := yaml.Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// example returns the key, nested in its sections, set to its default or an
+// empty value.
+func (k confKey) example() string {
+	value := k.defaultValue()
+	if value == "" {
+		switch k.typ.Kind() {
+		case reflect.Slice:
+			value = "[]"
+		case reflect.Map:
+			value = "{}"
+		case reflect.String:
+			value = `""`
+		default:
+			value = fmt.Sprint(reflect.Zero(k.typ).Interface())
+		}
+	}
+
+	// The first key of a list item is dashed, at the indentation of the
+	// item keys minus two.
+	var lines []string
+	indent, item := "", false
+	segments := strings.Split(k.path, ".")
+	for i, s := range segments {
+		list := strings.HasSuffix(s, "[]")
+		s = strings.TrimSuffix(s, "[]")
+		lead := indent
+		if item {
+			lead, item = indent[:len(indent)-2]+"- ", false
+		}
+		if i == len(segments)-1 {
+			lines = append(lines, lead+s+": "+value)
+			break
+		}
+		lines = append(lines, lead+s+":")
+		indent += "  "
+		item = list
+	}
+	return strings.Join(lines, "\n")
+}
+
+// walkConf calls fn for every yaml key of the struct type t, whose value
+// with defaults is v, and of its sections. Keys of lists are suffixed with
+// [], keys of maps are <name>.
+func walkConf(prefix string, t reflect.Type, v reflect.Value, fn func(confKey)) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" || f.PkgPath != "" {
+			continue
+		}
+		path := prefix + name
+		var fv reflect.Value
+		if v.IsValid() {
+			fv = v.Field(i)
+		}
+		fn(confKey{path: path, typ: f.Type, value: fv, doc: configDocs[t.Name()+"."+f.Name]})
+
+		ft, elem := f.Type, deref(fv)
+		switch {
+		case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+			walkConf(path+".", ft.Elem(), elem, fn)
+		case ft.Kind() == reflect.Slice && isSection(ft.Elem()):
+			var ev reflect.Value
+			if fv.IsValid() && fv.Len() > 0 {
+				ev = deref(fv.Index(0))
+			}
+			walkConf(path+"[].", indirect(ft.Elem()), ev, fn)
+		case ft.Kind() == reflect.Map && isSection(ft.Elem()):
+			var ev reflect.Value
+			if fv.IsValid() && fv.Len() > 0 {
+				ev = deref(fv.MapIndex(fv.MapKeys()[0]))
+			}
+			walkConf(path+".<name>.", indirect(ft.Elem()), ev, fn)
+		case ft.Kind() == reflect.Map && ft.Elem().Kind() == reflect.Slice && isSection(ft.Elem().Elem()):
+			walkConf(path+".<name>[].", indirect(ft.Elem().Elem()), reflect.Value{}, fn)
+		}
+	}
+}
+
+// fillSections sets every nil section of a struct, and a single item of
+// lists and maps of sections, so setting the defaults sets them all.
+func fillSections(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		ft := f.Type()
+		switch {
+		case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+			f.Set(reflect.New(ft.Elem()))
+			fillSections(f.Elem())
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Ptr && ft.Elem().Elem().Kind() == reflect.Struct:
+			item := reflect.New(ft.Elem().Elem())
+			fillSections(item.Elem())
+			f.Set(reflect.Append(reflect.MakeSlice(ft, 0, 1), item))
+		case ft.Kind() == reflect.Map && ft.Key().Kind() == reflect.String && ft.Elem().Kind() == reflect.Ptr && ft.Elem().Elem().Kind() == reflect.Struct:
+			item := reflect.New(ft.Elem().Elem())
+			fillSections(item.Elem())
+			f.Set(reflect.MakeMap(ft))
+			f.SetMapIndex(reflect.ValueOf("name").Convert(ft.Key()), item)
+		}
+	}
+}
+
+// typeName returns the name of a config type as written in YAML.
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		if t.Elem().Kind() == reflect.Struct {
+			return "section"
+		}
+		return typeName(t.Elem())
+	case reflect.Struct:
+		return "section"
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		if isSection(t.Elem()) {
+			return "list of sections"
+		}
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		if isSection(t.Elem()) {
+			return "map of " + typeName(t.Key()) + " to sections"
+		}
+		return "map of " + typeName(t.Key()) + " to " + typeName(t.Elem())
+	}
+	return t.String()
+}
+
+// isSection reports whether t is a struct or a pointer to one.
+func isSection(t reflect.Type) bool {
+	return indirect(t).Kind() == reflect.Struct
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// deref returns the value v points to, the zero Value when nil.
+func deref(v reflect.Value) reflect.Value {
+	if v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		return v.Elem()
+	}
+	return v
+}
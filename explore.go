@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/api/analytics/v3"
+)
+
+// explore runs a RealTime query with the exporter credentials and prints
+// the response as a table, to prototype config entries. It returns the
+// exit status.
+func explore(args []string) int {
+	fs := flag.NewFlagSet("explore", flag.ExitOnError)
+	viewID := fs.String("view", "", "GA view ID, with or without the ga: prefix.")
+	metric := fs.String("metric", "rt:activeUsers", "Comma separated RealTime metrics.")
+	dimensions := fs.String("dimensions", "", "Comma separated RealTime dimensions.")
+	sort := fs.String("sort", "", "Sort order, e.g. -rt:activeUsers.")
+	maxResults := fs.Int64("max-results", 0, "Maximum number of rows.")
+	fs.Parse(args)
+
+	if *viewID == "" {
+		fmt.Fprintln(os.Stderr, "explore: -view is required")
+		return 2
+	}
+	if !strings.HasPrefix(*viewID, "ga:") {
+		*viewID = "ga:" + *viewID
+	}
+
+	as, err := analytics.New(newHTTPClient())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "explore: %v\n", err)
+		return 1
+	}
+	v := newDryRunView(&viewConf{ViewID: *viewID}, nameTemplate)
+	m, err := v.query(analytics.NewDataRealtimeService(as), &realtimeQuery{
+		metric:     *metric,
+		dimensions: *dimensions,
+		sort:       *sort,
+		maxResults: *maxResults,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "explore: %v\n", err)
+		return 1
+	}
+
+	printTable(os.Stdout, m)
+	return 0
+}
+
+// printTable prints the rows of a response under their column names,
+// followed by the totals.
+func printTable(out io.Writer, m *analytics.RealtimeData) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	headers := make([]string, len(m.ColumnHeaders))
+	for i, h := range m.ColumnHeaders {
+		headers[i] = h.Name
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range m.Rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\n%d rows", len(m.Rows))
+	for _, name := range headers {
+		if total, ok := m.TotalsForAllResults[name]; ok {
+			fmt.Fprintf(out, ", %s total %s", name, total)
+		}
+	}
+	fmt.Fprintln(out)
+}
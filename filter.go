@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// valueFilter keeps the rows of a response by the value of a dimension
+// column, for filters GA-side filters can't express. Regular expressions are
+// anchored, as in Prometheus relabeling.
+type valueFilter struct {
+	// Include keeps only rows matching any of the expressions.
+	Include []string `yaml:"include"`
+	// Exclude drops rows matching any of the expressions.
+	Exclude []string `yaml:"exclude"`
+
+	include, exclude []*regexp.Regexp
+}
+
+// compile compiles the expressions of the filter.
+func (f *valueFilter) compile() error {
+	var err error
+	if f.include, err = compileAnchored(f.Include); err != nil {
+		return err
+	}
+	f.exclude, err = compileAnchored(f.Exclude)
+	return err
+}
+
+func compileAnchored(exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", expr, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// keep reports whether a value passes the filter.
+func (f *valueFilter) keep(value string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(value) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// newFilteredRows returns the counter of the rows dropped by the filters of
+// a view.
+func newFilteredRows(v *view) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "ga_exporter_filtered_rows_total",
+		Help:        "Rows dropped by the value filters of a dimension.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric", "dimension"})
+}
+
+// filterRow returns the first filtered dimension column of a row rejecting
+// it, "" when the row is kept.
+func (v *view) filterRow(columns []string, row []string) string {
+	for i, c := range columns {
+		if f, ok := v.Filters[c]; ok && f != nil && i < len(row) && !f.keep(row[i]) {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// freshnessCheckInterval is how often freshness SLOs are evaluated.
+const freshnessCheckInterval = 15 * time.Second
+
+var freshnessDesc = prometheus.NewDesc(
+	"ga_exporter_freshness_seconds",
+	"Time since the last successful update of a metric, or since startup if it never succeeded.",
+	[]string{"viewid", "alias", "metric"}, nil,
+)
+
+// freshnessCollector computes metric freshness at scrape time.
+type freshnessCollector struct{}
+
+func init() {
+	prometheus.MustRegister(freshnessCollector{})
+}
+
+// Describe implements prometheus.Collector.
+func (freshnessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- freshnessDesc
+}
+
+// Collect implements prometheus.Collector.
+func (freshnessCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, v := range getViews() {
+		for metric, age := range v.freshness(now) {
+			ch <- prometheus.MustNewConstMetric(freshnessDesc, prometheus.GaugeValue, age.Seconds(), v.ViewID, v.Alias, metric)
+		}
+	}
+}
+
+// markFresh records a successful update of a metric.
+func (v *view) markFresh(metric string) {
+	v.markFreshAt(metric, time.Now())
+}
+
+// markFreshAt records an update of a metric with data fetched at t.
+func (v *view) markFreshAt(metric string, t time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lastSuccess[metric] = t
+}
+
+// freshness returns the time since the last successful update per metric.
+func (v *view) freshness(now time.Time) map[string]time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	queries := v.queries()
+	ages := make(map[string]time.Duration, len(queries))
+	for _, metric := range queries {
+		last, ok := v.lastSuccess[metric]
+		if !ok {
+			last = startTime
+		}
+		ages[metric] = now.Sub(last)
+	}
+
+	return ages
+}
+
+// freshnessEvent is posted to the webhook when an SLO is violated or
+// recovers.
+type freshnessEvent struct {
+	Status    string  `json:"status"`
+	ViewID    string  `json:"viewid"`
+	Metric    string  `json:"metric"`
+	Freshness float64 `json:"freshness_seconds"`
+	SLO       float64 `json:"slo_seconds"`
+}
+
+// freshnessSLO warns when metrics were not updated for longer than slo.
+type freshnessSLO struct {
+	slo     time.Duration
+	webhook string
+
+	mu       sync.Mutex
+	violated map[string]bool
+}
+
+func newFreshnessSLO(slo time.Duration, webhook string) *freshnessSLO {
+	return &freshnessSLO{slo: slo, webhook: webhook, violated: make(map[string]bool)}
+}
+
+// run evaluates the SLO periodically, it never returns.
+func (f *freshnessSLO) run() {
+	for range time.Tick(freshnessCheckInterval) {
+		f.check(time.Now())
+	}
+}
+
+// check logs and notifies SLO violations and recoveries, once each.
+func (f *freshnessSLO) check(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Metrics are expected to go stale during maintenance.
+	if maintenanceWindows.active(now) {
+		return
+	}
+	for _, v := range getViews() {
+		for metric, age := range v.freshness(now) {
+			key := v.ViewID + " " + metric
+			violated := age > f.slo
+			if violated == f.violated[key] {
+				continue
+			}
+			f.violated[key] = violated
+
+			e := freshnessEvent{Status: "resolved", ViewID: v.ViewID, Metric: metric, Freshness: age.Seconds(), SLO: f.slo.Seconds()}
+			if violated {
+				e.Status = "violated"
+				log.Printf("view %s: %s: not updated for %s, freshness SLO is %s", v.displayName(), metric, age, f.slo)
+			} else {
+				log.Printf("view %s: %s: fresh again", v.displayName(), metric)
+			}
+			if f.webhook != "" {
+				go f.notify(e)
+			}
+		}
+	}
+}
+
+// notify posts an event to the webhook.
+func (f *freshnessSLO) notify(e freshnessEvent) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("freshness webhook: %v", err)
+		return
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(f.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("freshness webhook: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("freshness webhook: %s", resp.Status)
+	}
+}
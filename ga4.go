@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analyticsdata/v1beta"
+)
+
+// ga4TrendConf defines the per-minute trend collector of GA4 properties.
+type ga4TrendConf struct {
+	// Properties are GA4 property IDs.
+	Properties []string `yaml:"properties"`
+	// Metrics exported per minute. Defaults to screenPageViews.
+	Metrics []string `yaml:"metrics"`
+	// Minutes of the trend, up to 30, or 60 for Analytics 360 properties.
+	// Defaults to 30.
+	Minutes int64 `yaml:"minutes"`
+}
+
+// ga4TrendCollector exports the last minutes of GA4 realtime metrics as a
+// vector labeled by minutes_ago, for sparkline-style dashboards without
+// waiting for Prometheus history.
+type ga4TrendCollector struct {
+	conf   *ga4TrendConf
+	svc    *analyticsdata.Service
+	gauges map[string]*prometheus.GaugeVec
+}
+
+func newGA4TrendCollector(httpClient *http.Client, c *ga4TrendConf) (*ga4TrendCollector, error) {
+	if len(c.Metrics) == 0 {
+		c.Metrics = []string{"screenPageViews"}
+	}
+	if c.Minutes <= 0 {
+		c.Minutes = 30
+	}
+
+	svc, err := analyticsdata.New(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &ga4TrendCollector{conf: c, svc: svc, gauges: make(map[string]*prometheus.GaugeVec)}
+	for _, metric := range c.Metrics {
+		name := "ga4_" + invalidNameChars.ReplaceAllString(metric, "_") + "_per_minute"
+		g.gauges[metric] = newGaugeVec(name, fmt.Sprintf("GA4 %s per minute of the last minutes", metric), []string{"property", "minutes_ago"})
+		if err := registerAll(g.gauges[metric]); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+func (g *ga4TrendCollector) name() string { return "ga4_trend" }
+
+// collect queries the trend of every property.
+func (g *ga4TrendCollector) collect() error {
+	var metrics []*analyticsdata.Metric
+	for _, m := range g.conf.Metrics {
+		metrics = append(metrics, &analyticsdata.Metric{Name: m})
+	}
+
+	var errs []string
+	for _, property := range g.conf.Properties {
+		id := strings.TrimPrefix(property, "properties/")
+		resp, err := g.svc.Properties.RunRealtimeReport("properties/"+id, &analyticsdata.RunRealtimeReportRequest{
+			Dimensions:          []*analyticsdata.Dimension{{Name: "minutesAgo"}},
+			Metrics:             metrics,
+			MinuteRanges:        []*analyticsdata.MinuteRange{{StartMinutesAgo: g.conf.Minutes - 1, EndMinutesAgo: 0}},
+			ReturnPropertyQuota: true,
+		}).Do()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("property %s: %v", id, categorize(id, "", err)))
+			continue
+		}
+		recordGA4Quota(id, resp.PropertyQuota)
+
+		// Minutes without activity have no row, they are set to 0 so the
+		// trend has no gaps.
+		values := make(map[string][]float64)
+		for _, m := range g.conf.Metrics {
+			values[m] = make([]float64, g.conf.Minutes)
+		}
+		for _, row := range resp.Rows {
+			if len(row.DimensionValues) == 0 {
+				continue
+			}
+			ago, err := strconv.Atoi(row.DimensionValues[0].Value)
+			if err != nil || ago < 0 || int64(ago) >= g.conf.Minutes {
+				continue
+			}
+			for i, mv := range row.MetricValues {
+				if i >= len(resp.MetricHeaders) {
+					break
+				}
+				if vs, ok := values[resp.MetricHeaders[i].Name]; ok {
+					vs[ago], _ = strconv.ParseFloat(mv.Value, 64)
+				}
+			}
+		}
+
+		for metric, vs := range values {
+			for ago, valf := range vs {
+				g.gauges[metric].WithLabelValues(id, strconv.Itoa(ago)).Set(valf)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
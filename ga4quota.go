@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analyticsdata/v1beta"
+)
+
+var (
+	ga4QuotaRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ga_exporter_ga4_quota_remaining",
+		Help: "Remaining GA4 property quota as of the last request, e.g. tokens_per_hour.",
+	}, []string{"property", "quota"})
+	ga4TokensConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ga_exporter_ga4_tokens_consumed_total",
+		Help: "GA4 quota tokens consumed by the requests of the exporter.",
+	}, []string{"property"})
+	ga4TokensCharged = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ga_exporter_ga4_tokens_charged",
+		Help:    "GA4 quota tokens charged per request.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"property"})
+)
+
+func init() {
+	prometheus.MustRegister(ga4QuotaRemaining, ga4TokensConsumed, ga4TokensCharged)
+}
+
+// recordGA4Quota exports the property quota returned with a GA4 response,
+// to tune intervals against the token model.
+func recordGA4Quota(property string, q *analyticsdata.PropertyQuota) {
+	if q == nil {
+		return
+	}
+	for quota, s := range ga4QuotaStatuses(q) {
+		ga4QuotaRemaining.WithLabelValues(property, quota).Set(float64(s.Remaining))
+	}
+	// Every request is charged against the daily and hourly quotas alike.
+	if q.TokensPerDay != nil {
+		ga4TokensConsumed.WithLabelValues(property).Add(float64(q.TokensPerDay.Consumed))
+		ga4TokensCharged.WithLabelValues(property).Observe(float64(q.TokensPerDay.Consumed))
+	}
+}
+
+// ga4QuotaStatuses returns the statuses of a property quota by name, of
+// those returned.
+func ga4QuotaStatuses(q *analyticsdata.PropertyQuota) map[string]*analyticsdata.QuotaStatus {
+	statuses := make(map[string]*analyticsdata.QuotaStatus)
+	for quota, s := range map[string]*analyticsdata.QuotaStatus{
+		"tokens_per_day":                            q.TokensPerDay,
+		"tokens_per_hour":                           q.TokensPerHour,
+		"tokens_per_project_per_hour":               q.TokensPerProjectPerHour,
+		"concurrent_requests":                       q.ConcurrentRequests,
+		"server_errors_per_project_per_hour":        q.ServerErrorsPerProjectPerHour,
+		"potentially_thresholded_requests_per_hour": q.PotentiallyThresholdedRequestsPerHour,
+	} {
+		if s != nil {
+			statuses[quota] = s
+		}
+	}
+	return statuses
+}
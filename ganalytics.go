@@ -5,86 +5,275 @@ prometheus for scraping.
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	kitlog "github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/jwt"
 	"google.golang.org/api/analytics/v3"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	// defaultTokenURL is Google's OAuth 2.0 token endpoint.
+	defaultTokenURL = "https://oauth2.googleapis.com/token"
+	// defaultInterval is a minute.
+	defaultInterval = 60
+	// defaultCompactionInterval is an hour.
+	defaultCompactionInterval = 3600
+	// defaultApplicationName identifies the exporter in the User-Agent.
+	defaultApplicationName = "googleanalytics_exporter"
+)
+
+// version is set at build time, with -ldflags "-X main.version=...".
+var version = "dev"
+
 var (
-	credsfile    = os.Getenv("CRED_FILE")
-	conffile     = os.Getenv("CONFIG_FILE")
-	promGauge    = make(map[string]prometheus.Gauge)
-	promGaugeVec = make(map[string]*prometheus.GaugeVec)
-	config       = new(conf)
+	checkConfig  = flag.Bool("check-config", false, "Validate configured metrics and dimensions against the GA APIs and exit.")
+	canaryConf   = flag.String("config.canary", "", "Candidate config file whose queries are run in dry-run, reporting differences with the running config.")
+	canaryCycles = flag.Int("config.canary.cycles", 10, "Number of collection cycles the canary config is run for.")
+	webConfig    = flag.String("web.config.file", "", "Path to configuration file that can enable TLS or authentication.")
+
+	credsfile = os.Getenv("CRED_FILE")
+	conffile  = os.Getenv("CONFIG_FILE")
+	config    = new(conf)
+	views     []*view
+	viewsMu   sync.RWMutex
+	tenants   map[string]*tenant
 )
 
 // conf defines configuration parameters
 type conf struct {
+	// Interval in seconds between polls of the metrics without a schedule
+	// and runs of the collectors. Defaults to a minute.
 	Interval   int                   `yaml:"interval"`
 	Metrics    []string              `yaml:"metrics"`
 	Dimensions []map[string][]string `yaml:"dimensions"`
 	ViewID     string                `yaml:"viewid"`
 	PromPort   string                `yaml:"promport"`
+	AuditLog   string                `yaml:"audit_log"`
+
+	// MetricsPath is the path metrics are served on, / redirects to it.
+	MetricsPath string `yaml:"metrics_path"`
+	// ListenAddresses are host:port addresses the metrics are served on,
+	// instead of promport on all addresses.
+	ListenAddresses []string `yaml:"listen_addresses"`
+	// IPProtocol restricts listeners to ipv4 or ipv6.
+	IPProtocol string `yaml:"ip_protocol"`
+	// AdminAddress is a host:port the health, reload, profiling and config
+	// endpoints are served on, apart from the metrics.
+	AdminAddress string `yaml:"admin_address"`
+	// AdminToken is required as a bearer token by the admin actions,
+	// reload, pause and resume. Without it reload is open to whoever
+	// reaches the admin listener, pause and resume are disabled.
+	AdminToken string `yaml:"admin_token"`
+
+	DailyQuota int `yaml:"daily_quota"`
+
+	// ErrorBufferSize is the number of collection errors served on
+	// /errors. Defaults to 100.
+	ErrorBufferSize int `yaml:"error_buffer_size"`
+
+	// FreshnessSLO in seconds, metrics not updated for longer are logged
+	// and notified to FreshnessWebhook.
+	FreshnessSLO     int    `yaml:"freshness_slo"`
+	FreshnessWebhook string `yaml:"freshness_webhook"`
+
+	// MaintenanceWindows are recurring windows during which nothing is
+	// collected, e.g. while GA reprocesses a property.
+	MaintenanceWindows []*maintenanceConf `yaml:"maintenance_windows"`
+
+	// MaxRuntime in seconds after which the exporter exits, for a
+	// supervisor to restart it.
+	MaxRuntime int `yaml:"max_runtime"`
+	// CompactionInterval in seconds, metric vectors not updated for as
+	// long are dropped. Defaults to an hour.
+	CompactionInterval int `yaml:"compaction_interval"`
+
+	// ScrapeTrigger runs the collection after scrapes of the metrics,
+	// instead of every second.
+	ScrapeTrigger *scrapeTriggerConf `yaml:"scrape_trigger"`
+
+	// Concurrency bounds the queries and collectors run at once.
+	Concurrency int `yaml:"concurrency"`
+	// CycleTimeout in seconds bounds a collection cycle, the queries not
+	// done by then are cancelled and run again next cycle. No timeout by
+	// default.
+	CycleTimeout int `yaml:"cycle_timeout"`
+
+	// StrictMetrics only exports the metrics and dimensions declared in the
+	// config, e.g. no metrics named after event actions.
+	StrictMetrics bool `yaml:"strict_metrics"`
+
+	// SeriesLimit caps the label combinations exported across all views,
+	// new ones are dropped once reached.
+	SeriesLimit int `yaml:"series_limit"`
+
+	// CacheDir keeps the last responses on disk, exported at startup until
+	// fresh data is fetched.
+	CacheDir string `yaml:"cache_dir"`
+
+	// HandoffSocket is a Unix socket the metric values are handed off on
+	// to the next process, avoiding a gap on restarts.
+	HandoffSocket string `yaml:"handoff_socket"`
+
+	// MetricNameTemplate is a Go template naming the GA metrics.
+	MetricNameTemplate string `yaml:"metric_name_template"`
+
+	// Alternative to the CRED_FILE json key, for secret stores providing
+	// the service account email and PEM private key separately.
+	ClientEmail    string `yaml:"client_email"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+
+	// Proxy is the URL of the proxy to Google APIs, socks5://, http:// or
+	// https://. Defaults to the HTTPS_PROXY environment variable.
+	Proxy    string        `yaml:"proxy"`
+	Resolver *resolverConf `yaml:"resolver"`
+	// OutboundTLS sets a CA bundle and client certificate for connections
+	// to Google APIs.
+	OutboundTLS *outboundTLSConf `yaml:"outbound_tls"`
+
+	// ApplicationName identifies the exporter in the User-Agent of API
+	// calls, e.g. for Google support on quota tickets.
+	ApplicationName string `yaml:"application_name"`
+
+	// Scopes overrides the OAuth scopes derived from enabled collectors.
+	Scopes          []string `yaml:"scopes"`
+	EnforceReadonly bool     `yaml:"enforce_readonly"`
+
+	// ValidHostnames restricts the queries of every view to traffic of
+	// these hostnames, discarding ghost spam.
+	ValidHostnames []string `yaml:"valid_hostnames"`
+
+	// Views lists additional GA views, each with its own metrics. The
+	// top level viewid/metrics/dimensions are kept as the first view.
+	Views   []*viewConf   `yaml:"views"`
+	Tenants []*tenantConf `yaml:"tenants"`
+	// Consul adds the views of a KV prefix, applied as they change.
+	Consul *consulConf `yaml:"consul"`
+
+	// Optional collectors, enabled when their section is present.
+	SearchConsole *gscConf `yaml:"searchconsole"`
+	GoogleAds     *adsConf `yaml:"googleads"`
+	YouTube       *ytConf  `yaml:"youtube"`
+	// GA4Trend exports the last minutes of GA4 properties per minute.
+	GA4Trend *ga4TrendConf `yaml:"ga4_trend"`
+
+	// Reports are Reporting API queries, polled on their own interval.
+	Reports []*reportConf `yaml:"reports"`
+	// Snapshots write the final values of every day of the reports to
+	// files.
+	Snapshots *snapshotConf `yaml:"snapshots"`
+	// BigQuery streams the values collected every cycle to a table.
+	BigQuery *bigqueryConf `yaml:"bigquery"`
+	// VictoriaMetrics receives the values collected every cycle.
+	VictoriaMetrics *victoriaMetricsConf `yaml:"victoriametrics"`
+	// Graphite receives the values of all metrics periodically.
+	Graphite *graphiteConf `yaml:"graphite"`
+	// LabelSnapshots keeps the label values observed per dimension, with
+	// when they were first and last seen.
+	LabelSnapshots *labelSnapshotConf `yaml:"label_snapshots"`
+	// ExpositionArchive uploads the exposition periodically.
+	ExpositionArchive *archiveConf `yaml:"exposition_archive"`
+
+	// Plugins are external binaries contributing metrics every cycle.
+	Plugins []*pluginConf `yaml:"plugins"`
+
+	// hash identifies the content of the configuration file.
+	hash string
 }
 
 func init() {
+	// The init subcommand writes the config file, the aggregate one
+	// doesn't collect and explain documents it. The tests build what they
+	// use.
+	if len(os.Args) > 1 && (os.Args[1] == "init" || os.Args[1] == "aggregate" || os.Args[1] == "explain") {
+		return
+	}
+	if strings.HasSuffix(os.Args[0], ".test") {
+		return
+	}
 	config.getConf(conffile)
+	configLoaded(config.hash)
+	tenants = newTenants(config.Tenants)
 
-	// All metrics are registered as Prometheus Gauge
-	for _, metric := range config.Metrics {
-		promGauge[metric] = prometheus.NewGauge(prometheus.GaugeOpts{
-			Name:        fmt.Sprintf("ga_%s", strings.Replace(metric, ":", "_", 1)),
-			Help:        fmt.Sprintf("Google Analytics %s", metric),
-			ConstLabels: map[string]string{"job": "googleAnalytics"},
-		})
+	config.setDefaults()
+	dailyQuota.Set(float64(config.DailyQuota))
+	recentErrors = newErrorRing(config.ErrorBufferSize)
+	if config.SeriesLimit > 0 {
+		globalSeriesLimit = &seriesLimit{max: config.SeriesLimit}
+	}
 
-		prometheus.Register(promGauge[metric])
+	var err error
+	if nameTemplate, err = parseNameTemplate(config.MetricNameTemplate); err != nil {
+		panic(fmt.Sprintf("metric_name_template: %v", err))
+	}
+
+	if len(config.MaintenanceWindows) > 0 {
+		if maintenanceWindows, err = newMaintenance(config.MaintenanceWindows); err != nil {
+			panic(err)
+		}
 	}
-}
 
-func registerMetricVec(metric string) {
-	promGaugeVec[metric] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name:        fmt.Sprintf("ga_%s", strings.Replace(metric, ":", "_", 1)),
-		Help:        fmt.Sprintf("Google Analytics %s", metric),
-		ConstLabels: map[string]string{"job": "googleAnalytics"},
-	}, []string{"category"})
+	if config.AuditLog != "" {
+		if auditLog, err = newAuditLogger(config.AuditLog); err != nil {
+			panic(err)
+		}
+	}
 
-	if err := prometheus.Register(promGaugeVec[metric]); err != nil {
-		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
-			promGaugeVec[metric] = are.ExistingCollector.(*prometheus.GaugeVec)
-		} else {
+	if config.CacheDir != "" {
+		if responseDiskCache, err = newDiskCache(config.CacheDir); err != nil {
 			panic(err)
 		}
 	}
+
+	for _, vc := range config.Views {
+		v := newView(vc)
+		if _, ok := tenants[vc.Tenant]; vc.Tenant != "" && !ok {
+			panic(fmt.Sprintf("view %s: unknown tenant %q", vc.displayName(), vc.Tenant))
+		}
+		views = append(views, v)
+	}
 }
 
 func main() {
-	creds := getCreds(credsfile)
+	flag.Parse()
+	switch flag.Arg(0) {
+	case "selftest":
+		os.Exit(selftest(flag.Args()[1:]))
+	case "verify":
+		os.Exit(verify(flag.Args()[1:]))
+	case "explore":
+		os.Exit(explore(flag.Args()[1:]))
+	case "init":
+		os.Exit(wizard(flag.Args()[1:]))
+	case "aggregate":
+		os.Exit(aggregate(flag.Args()[1:]))
+	case "migrate":
+		os.Exit(migrate(flag.Args()[1:]))
+	case "explain":
+		os.Exit(explain(flag.Args()[1:]))
+	}
 
-	// JSON web token configuration
-	jwtc := jwt.Config{
-		Email:        creds["client_email"],
-		PrivateKey:   []byte(creds["private_key"]),
-		PrivateKeyID: creds["private_key_id"],
-		Scopes:       []string{analytics.AnalyticsReadonlyScope},
-		TokenURL:     creds["token_uri"],
-		// Expires:      time.Duration(1) * time.Hour, // Expire in 1 hour
+	if err := web.Validate(*webConfig); err != nil {
+		panic(fmt.Sprintf("web.config.file: %v", err))
 	}
 
-	httpClient := jwtc.Client(oauth2.NoContext)
+	httpClient := newHTTPClient()
 	as, err := analytics.New(httpClient)
 	if err != nil {
 		panic(err)
@@ -93,78 +282,437 @@ func main() {
 	// Authenticated RealTime Google Analytics API service
 	rts := analytics.NewDataRealtimeService(as)
 
-	// Expose the registered metrics via HTTP.
-	http.Handle("/metrics", promhttp.Handler())
+	problems, err := validateConfig(as, rts)
+	if err != nil {
+		log.Printf("config validation: %v", err)
+	}
+	for _, p := range problems {
+		log.Printf("config validation: %s", p)
+	}
+	if *checkConfig {
+		if err != nil || len(problems) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("config OK")
+		os.Exit(0)
+	}
+	// Invalid metrics are left out, the others are collected. A rejected
+	// filter would fail every query of the view, it is a config error.
+	for _, p := range problems {
+		if p.Filters != "" {
+			log.Fatalf("config validation: %s", p)
+		}
+		for _, v := range views {
+			if v.ViewID == p.ViewID {
+				v.disable(p.Metric, p.Problem)
+			}
+		}
+	}
 
-	go http.ListenAndServe(fmt.Sprintf(":%s", config.PromPort), nil)
+	var cn *canary
+	if *canaryConf != "" {
+		if cn, err = newCanary(*canaryConf, *canaryCycles); err != nil {
+			panic(err)
+		}
+	}
 
-	for {
-		for _, metric := range config.Metrics {
-			// Go routine per metric
-			go func(metric string) {
-				dimensions := getDimensions(metric)
-				collectMetric(rts, metric, dimensions)
-			}(metric)
+	var collectors []collector
+	if config.SearchConsole != nil {
+		gsc, err := newGSCCollector(httpClient, config.SearchConsole)
+		if err != nil {
+			panic(err)
 		}
-		time.Sleep(time.Second * time.Duration(config.Interval))
+		collectors = append(collectors, gsc)
+	}
+	if config.GoogleAds != nil {
+		ads, err := newAdsCollector(httpClient, config.GoogleAds)
+		if err != nil {
+			panic(err)
+		}
+		collectors = append(collectors, ads)
+	}
+	if config.YouTube != nil {
+		yt, err := newYTCollector(httpClient, config.YouTube)
+		if err != nil {
+			panic(err)
+		}
+		collectors = append(collectors, yt)
+	}
+	if config.GA4Trend != nil {
+		g, err := newGA4TrendCollector(httpClient, config.GA4Trend)
+		if err != nil {
+			panic(err)
+		}
+		collectors = append(collectors, g)
+	}
+	zones, err := newViewTimezones(as)
+	if err != nil {
+		panic(err)
+	}
+	collectors = append(collectors, zones)
+	var reports *reportCollector
+	if len(config.Reports) > 0 {
+		if reports, err = newReportCollector(httpClient, config.Reports, zones); err != nil {
+			panic(err)
+		}
+		collectors = append(collectors, reports)
+	}
+	if config.Snapshots != nil {
+		s, err := newSnapshotCollector(httpClient, reports, config.Snapshots)
+		if err != nil {
+			panic(err)
+		}
+		collectors = append(collectors, s)
+	}
+	if config.ExpositionArchive != nil {
+		a, err := newArchiveCollector(httpClient, config.ExpositionArchive)
+		if err != nil {
+			panic(err)
+		}
+		collectors = append(collectors, a)
 	}
-}
 
-// getMetric queries GA RealTime API for a specific metric.
-func collectMetric(rts *analytics.DataRealtimeService, metric string, gaDimensions string) {
-	getc := rts.Get(config.ViewID, metric)
+	var bq *bigquerySink
+	if config.BigQuery != nil {
+		if bq, err = newBigquerySink(httpClient, config.BigQuery); err != nil {
+			panic(err)
+		}
+	}
+	var vm *vmSink
+	if config.VictoriaMetrics != nil {
+		vm = newVMSink(config.VictoriaMetrics)
+	}
+	if config.LabelSnapshots != nil {
+		if labelSnapshots, err = newLabelTracker(config.LabelSnapshots); err != nil {
+			panic(err)
+		}
+		if config.LabelSnapshots.Path != "" {
+			go labelSnapshots.run()
+		}
+	}
 
-	if len(gaDimensions) > 0 {
-		getc.Dimensions(gaDimensions)
+	// Views with a content, campaigns or goal_values section, or a device
+	// split, may be added on reload.
+	collectors = append(collectors, &contentCollector{rts: rts}, &campaignCollector{rts: rts}, &deviceCollector{rts: rts}, newGoalCollector(as, rts))
+	for _, pc := range config.Plugins {
+		p, err := newPluginCollector(pc)
+		if err != nil {
+			panic(err)
+		}
+		collectors = append(collectors, p)
 	}
 
-	m, err := getc.Do()
+	var trigger *scrapeTrigger
+	if config.ScrapeTrigger != nil {
+		trigger = newScrapeTrigger(config.ScrapeTrigger)
+	}
+
+	// Expose the registered metrics via HTTP. Views of tenants are only
+	// served on the tenant path.
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, viewsGatherer("")}
+	mux := http.NewServeMux()
+	mux.Handle(config.MetricsPath, trigger.handler(promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})))
+	for _, t := range tenants {
+		mux.Handle(strings.TrimSuffix(config.MetricsPath, "/")+"/"+t.Name, trigger.handler(t.handler()))
+	}
+	mux.HandleFunc("/readyz", readyzHandler)
+	// Without admin listener, errors, series counts, label values and the
+	// build provenance are served with the metrics, the series counts of
+	// tenant views only to holders of the tenant token.
+	if config.AdminAddress == "" {
+		mux.HandleFunc("/errors", errorsHandler)
+		mux.HandleFunc("/cardinality", cardinalityHandler(false))
+		mux.HandleFunc("/labels", labelsHandler)
+		mux.HandleFunc("/buildinfo", buildInfoHandler)
+	}
+	if config.MetricsPath != "/" {
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			http.Redirect(w, r, config.MetricsPath, http.StatusFound)
+		})
+	}
+
+	// With a handoff socket, the port is shared with the previous process
+	// until it has handed off its metric values.
+	addrs := config.ListenAddresses
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf(":%s", config.PromPort)}
+	}
+	ls, err := listen(config.ipNetwork(), addrs, config.HandoffSocket != "")
 	if err != nil {
 		panic(err)
 	}
+	if config.HandoffSocket != "" {
+		receiveHandoff(config.HandoffSocket, getViews(), time.Now().Add(time.Duration(config.CompactionInterval)*time.Second))
+		if err := serveHandoff(config.HandoffSocket); err != nil {
+			panic(err)
+		}
+	}
+	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
+	go func() {
+		if err := web.ServeMultiple(ls, &http.Server{Handler: mux}, &web.FlagConfig{WebConfigFile: webConfig}, logger); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	if config.AdminAddress != "" {
+		als, err := listen(config.ipNetwork(), []string{config.AdminAddress}, config.HandoffSocket != "")
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			if err := web.ServeMultiple(als, &http.Server{Handler: newAdminMux()}, &web.FlagConfig{WebConfigFile: webConfig}, logger); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	go reloadOnSIGHUP()
+	if config.Consul != nil {
+		go newConsulWatcher(config.Consul).run()
+	}
 
-	if len(m.Rows) == 1 {
-		valf, _ := strconv.ParseFloat(m.Rows[0][0], 64)
-		promGauge[metric].Set(valf)
-		return
+	if config.Graphite != nil {
+		go (&graphiteSink{conf: config.Graphite}).run()
 	}
+	if config.FreshnessSLO > 0 {
+		go newFreshnessSLO(time.Duration(config.FreshnessSLO)*time.Second, config.FreshnessWebhook).run()
+	}
+
+	// Metrics are polled on their own schedule, checked every second, or
+	// after every scrape with a scrape trigger, the other collectors every
+	// interval. Due queries and collectors run in parallel, the next check
+	// waits for all of them.
+	var nextCycle time.Time
+	compaction := time.Duration(config.CompactionInterval) * time.Second
+	nextCompaction := time.Now().Add(compaction)
+	for {
+		now := time.Now()
+		vs := getViews()
+		if config.MaxRuntime > 0 && now.Sub(startTime) >= time.Duration(config.MaxRuntime)*time.Second {
+			log.Printf("max_runtime of %ds reached, exiting", config.MaxRuntime)
+			return
+		}
+		// While paused or in a maintenance window nothing is queried nor
+		// compacted, the last values are served. Compaction waits for
+		// fresh values after resuming.
+		if isPaused() || maintenanceWindows.active(now) {
+			nextCompaction = now.Add(compaction)
+			time.Sleep(time.Second)
+			continue
+		}
+		if !now.Before(nextCompaction) {
+			for _, v := range vs {
+				if n := v.compact(now.Add(-compaction)); n > 0 {
+					log.Printf("view %s: dropped %d unused metric vectors", v.displayName(), n)
+				}
+			}
+			nextCompaction = now.Add(compaction)
+		}
 
-	for _, row := range m.Rows {
-		category := row[0]
-		if !strings.Contains(category, "(not set)") {
-			label := buildMetricLabel(row[1])
-			registerMetricVec(label)
-			valf, _ := strconv.ParseFloat(row[2], 64)
-			promGaugeVec[label].WithLabelValues(category).Set(valf)
+		// Scrapes don't land exactly an interval apart, what is due
+		// before the next one runs now.
+		at := now.Add(trigger.lead())
+		var tasks []task
+		var collected []*view
+		hasDue := make(map[*view]bool)
+		for _, v := range vs {
+			due := v.dueMetrics(at)
+			if len(due) > 0 {
+				collected = append(collected, v)
+				hasDue[v] = true
+			}
+			for _, metric := range due {
+				v, metric := v, metric
+				tasks = append(tasks, task{
+					name:      fmt.Sprintf("view %s: %s", v.displayName(), metric),
+					run:       func() error { return v.collectMetric(rts, metric, v.getDimensions(metric)) },
+					cancelled: func() { v.reschedule(metric) },
+				})
+			}
+		}
+
+		if !at.Before(nextCycle) {
+			for _, c := range collectors {
+				tasks = append(tasks, task{name: c.name(), run: c.collect})
+			}
+			// Views only updated by the collectors are collected too.
+			for _, v := range vs {
+				if !hasDue[v] && v.collected() {
+					collected = append(collected, v)
+				}
+			}
+			if cn != nil {
+				go cn.run(rts)
+			}
+			nextCycle = at.Add(time.Second * time.Duration(config.Interval))
+		}
+		runCycle(tasks, config.Concurrency, time.Duration(config.CycleTimeout)*time.Second)
+		if bq != nil && len(collected) > 0 {
+			go bq.write(now, collected)
 		}
+		if vm != nil && len(collected) > 0 {
+			go vm.write(now, collected)
+		}
+		if labelSnapshots != nil && len(collected) > 0 {
+			go labelSnapshots.observe(now, collected)
+		}
+		trigger.wait()
 	}
 }
 
+// newHTTPClient returns a client authenticated with the service account
+// credentials.
+func newHTTPClient() *http.Client {
+	var creds map[string]string
+	if config.PrivateKeyFile != "" {
+		creds = getPEMCreds(config.ClientEmail, config.PrivateKeyFile)
+	} else {
+		creds = getCreds(credsfile)
+	}
+
+	scopes, err := config.getScopes()
+	if err != nil {
+		panic(err)
+	}
+
+	// JSON web token configuration
+	jwtc := jwt.Config{
+		Email:        creds["client_email"],
+		PrivateKey:   []byte(creds["private_key"]),
+		PrivateKeyID: creds["private_key_id"],
+		Scopes:       scopes,
+		TokenURL:     creds["token_uri"],
+		// Expires:      time.Duration(1) * time.Hour, // Expire in 1 hour
+	}
+
+	tr, err := config.newTransport()
+	if err != nil {
+		panic(err)
+	}
+	base := &http.Client{Transport: &userAgentTransport{base: tr, userAgent: config.userAgent()}}
+
+	ts, err := newTokenSource(&jwtc, base)
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.WithValue(oauth2.NoContext, oauth2.HTTPClient, base)
+	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, ts))
+}
+
+// buildMetricLabel builds a metric name from a dimension value, e.g. an
+// event action. Non-latin values are transliterated rather than stripped.
 func buildMetricLabel(action string) string {
 	reg, _ := regexp.Compile("[^a-zA-Z0-9]+")
-	rows := []string{"rt:", reg.ReplaceAllString(action, "")}
+	rows := []string{"rt:", reg.ReplaceAllString(transliterate(action), "")}
 
 	return strings.Replace(strings.Join(rows, "_"), " ", "_", -1)
 }
 
-// getDimensions gets dimensions from one specific metric.
-func getDimensions(metric string) string {
-	var dimensions string
-	for _, dimensionMap := range config.Dimensions {
-		dimensions = strings.Join(dimensionMap[metric][:], ",")
+// conf.getConf reads yaml configuration file
+func (c *conf) getConf(filename string) {
+	nc, err := readConf(filename)
+	if err != nil {
+		panic(err)
 	}
-
-	return dimensions
+	*c = *nc
 }
 
-// conf.getConf reads yaml configuration file
-func (c *conf) getConf(filename string) {
+// readConf reads and checks a yaml configuration file.
+func readConf(filename string) (*conf, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	if err = yaml.Unmarshal(data, &c); err != nil {
-		panic(err)
+	// Unknown and duplicate keys are rejected, they are most likely typos.
+	c := new(conf)
+	if err = yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, err
+	}
+
+	if c.ViewID != "" {
+		legacy := &viewConf{ViewID: c.ViewID, Metrics: c.Metrics, Dimensions: c.Dimensions}
+		c.Views = append([]*viewConf{legacy}, c.Views...)
+	}
+	if c.Consul != nil {
+		c.Views = append(c.Views, consulViews(c)...)
+	}
+	if err = c.check(); err != nil {
+		return nil, err
+	}
+	c.hash = configHashOf(data)
+
+	return c, nil
+}
+
+// ipNetwork returns the network of the listeners from ip_protocol.
+func (c *conf) ipNetwork() string {
+	switch c.IPProtocol {
+	case "ipv4":
+		return "tcp4"
+	case "ipv6":
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// setDefaults sets the defaults of unset settings.
+func (c *conf) setDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	if c.DailyQuota <= 0 {
+		c.DailyQuota = defaultDailyQuota
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultConcurrency
+	}
+	if c.CompactionInterval <= 0 {
+		c.CompactionInterval = defaultCompactionInterval
+	}
+	if c.Snapshots != nil {
+		if c.Snapshots.Format == "" {
+			c.Snapshots.Format = "csv"
+		}
+		if c.Snapshots.Days <= 0 {
+			c.Snapshots.Days = defaultSnapshotDays
+		}
+	}
+	if c.ExpositionArchive != nil && c.ExpositionArchive.Interval <= 0 {
+		c.ExpositionArchive.Interval = defaultArchiveInterval
+	}
+	if c.ScrapeTrigger != nil && c.ScrapeTrigger.Debounce <= 0 {
+		c.ScrapeTrigger.Debounce = defaultScrapeDebounce
+	}
+	if c.Consul != nil && c.Consul.Address == "" {
+		c.Consul.Address = defaultConsulAddress
+	}
+	if c.Graphite != nil && c.Graphite.Interval <= 0 {
+		c.Graphite.Interval = defaultGraphiteInterval
+	}
+	if c.LabelSnapshots != nil {
+		if c.LabelSnapshots.Interval <= 0 {
+			c.LabelSnapshots.Interval = defaultLabelSnapshotInterval
+		}
+		if c.LabelSnapshots.Retention <= 0 {
+			c.LabelSnapshots.Retention = defaultLabelSnapshotRetention
+		}
+	}
+	if c.ErrorBufferSize <= 0 {
+		c.ErrorBufferSize = defaultErrorBufferSize
+	}
+	if c.MetricsPath == "" {
+		c.MetricsPath = "/metrics"
+	}
+	c.MetricsPath = "/" + strings.Trim(c.MetricsPath, "/")
+	for _, vc := range c.Views {
+		if len(vc.ValidHostnames) == 0 {
+			vc.ValidHostnames = c.ValidHostnames
+		}
+		vc.setDefaults()
 	}
 }
 
@@ -182,3 +730,21 @@ func getCreds(filename string) (r map[string]string) {
 
 	return r
 }
+
+// getPEMCreds builds creds from a service account email and the PEM private
+// key of that account.
+func getPEMCreds(email, filename string) map[string]string {
+	if email == "" {
+		panic("client_email is required with private_key_file")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	return map[string]string{
+		"client_email": email,
+		"private_key":  string(data),
+		"token_uri":    defaultTokenURL,
+	}
+}
@@ -0,0 +1,98 @@
+//go:build ignore
+// +build ignore
+
+// gen_configdoc writes configdoc.go, the doc comments of the fields of the
+// config structs printed by the explain subcommand. Run by go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != "configdoc.go"
+	}, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	docs := make(map[string]string)
+	for _, f := range pkgs["main"].Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			ts, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			// A comment can document the following fields too, e.g.
+			// "Min and Max clamp the values.", when it names them.
+			var group string
+			for _, field := range st.Fields.List {
+				if field.Tag == nil || len(field.Names) == 0 {
+					continue
+				}
+				tag, _ := strconv.Unquote(field.Tag.Value)
+				if reflect.StructTag(tag).Get("yaml") == "" {
+					continue
+				}
+				name := field.Names[0].Name
+				doc := field.Doc.Text()
+				if doc == "" {
+					doc = field.Comment.Text()
+				}
+				doc = strings.Join(strings.Fields(doc), " ")
+				if doc != "" {
+					group = doc
+				} else if strings.Contains(" "+group+" ", " "+name+" ") {
+					doc = group
+				}
+				if doc != "" {
+					docs[ts.Name.Name+"."+name] = doc
+				}
+			}
+			return false
+		})
+	}
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by gen_configdoc.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package main")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "// configDocs are the doc comments of the config fields, by struct and field.")
+	fmt.Fprintln(&buf, "var configDocs = map[string]string{")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%q: %q,\n", key, docs[key])
+	}
+	fmt.Fprintln(&buf, "}")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("configdoc.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
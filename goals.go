@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+)
+
+// goalValueConf defines the realtime goal value collector of a view.
+type goalValueConf struct {
+	// Goals are the goal numbers exported, 1 to 20.
+	Goals []int `yaml:"goals"`
+	// Names of the goals, by number, exported as the goal label. Goals
+	// without name are named as in the view settings.
+	Names map[int]string `yaml:"names"`
+	// Scale multiplies the values, which are in the currency of the view,
+	// e.g. to convert them to a common currency. Defaults to 1.
+	Scale float64 `yaml:"scale"`
+}
+
+// goalCollector exports the realtime value of the goals of every view with
+// a goal_values section.
+type goalCollector struct {
+	as  *analytics.Service
+	rts *analytics.DataRealtimeService
+
+	mu sync.Mutex
+	// names are the goal names of the view settings, by view and goal
+	// number, looked up once.
+	names map[string]map[string]string
+}
+
+func newGoalCollector(as *analytics.Service, rts *analytics.DataRealtimeService) *goalCollector {
+	return &goalCollector{as: as, rts: rts, names: make(map[string]map[string]string)}
+}
+
+// newGoalValueVec returns the vector of the goal collector of a view.
+func newGoalValueVec(v *view) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_goal_value",
+		Help:        "Realtime value of a goal, in the currency of the view times the scale.",
+		ConstLabels: v.constLabels(),
+	}, []string{"goal"})
+}
+
+func (c *goalCollector) name() string { return "goal_values" }
+
+// collect queries the goal values of every view, all goals of a view in a
+// single query.
+func (c *goalCollector) collect() error {
+	var errs []string
+	for _, v := range getViews() {
+		if v.GoalValues == nil || len(v.GoalValues.Goals) == 0 {
+			continue
+		}
+		metrics := make([]string, len(v.GoalValues.Goals))
+		for i, g := range v.GoalValues.Goals {
+			metrics[i] = fmt.Sprintf("rt:goal%dValue", g)
+		}
+		m, err := v.query(c.rts, &realtimeQuery{metric: strings.Join(metrics, ",")})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("view %s: %v", v.displayName(), err))
+			continue
+		}
+
+		// Without row, no goal was completed.
+		values := make([]string, len(metrics))
+		if len(m.Rows) > 0 {
+			values = m.Rows[0]
+		}
+		vec := v.goalValueVec
+		vec.Reset()
+		for i, g := range v.GoalValues.Goals {
+			if i >= len(values) {
+				break
+			}
+			valf, _ := strconv.ParseFloat(values[i], 64)
+			vec.WithLabelValues(c.goalName(v, g)).Set(valf * v.GoalValues.Scale)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// goalName returns the name of a goal of a view: from the config, else from
+// the view settings, else its number.
+func (c *goalCollector) goalName(v *view, goal int) string {
+	if name := v.GoalValues.Names[goal]; name != "" {
+		return name
+	}
+	id := strconv.Itoa(goal)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names, ok := c.names[v.ViewID]
+	if !ok {
+		goals, err := c.as.Management.Goals.List("~all", "~all", strings.TrimPrefix(v.ViewID, "ga:")).Do()
+		if err != nil {
+			// Looked up again next cycle.
+			log.Printf("view %s: goal names: %v", v.displayName(), err)
+			return id
+		}
+		names = make(map[string]string)
+		for _, g := range goals.Items {
+			names[g.Id] = sanitizeLabelValue(g.Name)
+		}
+		c.names[v.ViewID] = names
+	}
+	if name := names[id]; name != "" {
+		return name
+	}
+	return id
+}
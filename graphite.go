@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultGraphiteInterval is a minute.
+const defaultGraphiteInterval = 60
+
+// graphiteConf defines the Graphite server all metrics are sent to, with
+// the plaintext protocol.
+type graphiteConf struct {
+	// Address is the host:port of the plaintext listener, e.g.
+	// graphite:2003.
+	Address string `yaml:"address"`
+	// Prefix of the metric paths, e.g. ga.prod.
+	Prefix string `yaml:"prefix"`
+	// Interval in seconds between flushes. Defaults to a minute.
+	Interval int `yaml:"interval"`
+	// Tags sends the labels as Graphite tags, name;label=value, instead
+	// of path nodes, name.label.value.
+	Tags bool `yaml:"tags"`
+}
+
+var (
+	graphiteSamples = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_graphite_samples_total",
+		Help: "Samples sent to Graphite.",
+	})
+	graphiteFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_graphite_failed_flushes_total",
+		Help: "Flushes to Graphite that failed.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(graphiteSamples, graphiteFailures)
+}
+
+// invalidGraphiteChars are replaced in path nodes and tag values.
+var invalidGraphiteChars = regexp.MustCompile(`[^a-zA-Z0-9_:-]`)
+
+// graphiteSink flushes the values of all metrics, of every tenant, to
+// Graphite periodically, for dashboards not on Prometheus yet.
+type graphiteSink struct {
+	conf *graphiteConf
+}
+
+// run flushes every interval, it never returns. Failures are logged, the
+// next flush sends the current values again.
+func (g *graphiteSink) run() {
+	for range time.Tick(time.Duration(g.conf.Interval) * time.Second) {
+		if err := g.flush(time.Now()); err != nil {
+			log.Printf("graphite: %v", err)
+			graphiteFailures.Inc()
+		}
+	}
+}
+
+// flush sends the current values at ts.
+func (g *graphiteSink) flush(ts time.Time) error {
+	mfs, err := allGatherers().Gather()
+	if err != nil {
+		log.Printf("graphite: %v", err)
+	}
+
+	conn, err := net.DialTimeout("tcp", g.conf.Address, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	w := bufio.NewWriter(conn)
+	n := 0
+	for _, mf := range mfs {
+		for _, fs := range flatten(mf) {
+			if math.IsNaN(fs.value) || math.IsInf(fs.value, 0) {
+				continue
+			}
+			fmt.Fprintf(w, "%s %g %d\n", g.path(fs), fs.value, ts.Unix())
+			n++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	graphiteSamples.Add(float64(n))
+	return nil
+}
+
+// path returns the Graphite path of a sample, its labels sorted by name
+// either as path nodes or as tags.
+func (g *graphiteSink) path(fs flatSample) string {
+	names := make([]string, 0, len(fs.labels))
+	for name := range fs.labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	path := fs.name
+	if g.conf.Prefix != "" {
+		path = strings.TrimSuffix(g.conf.Prefix, ".") + "." + path
+	}
+	for _, name := range names {
+		value := invalidGraphiteChars.ReplaceAllString(fs.labels[name], "_")
+		if value == "" {
+			continue
+		}
+		if g.conf.Tags {
+			path += ";" + name + "=" + value
+		} else {
+			path += "." + name + "." + value
+		}
+	}
+	return path
+}
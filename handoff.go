@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// warmGatherer serves the values handed off by the previous process for
+// the metrics of a view that were not collected yet, so a restart doesn't
+// show as a gap or a dip to zero.
+type warmGatherer struct {
+	live prometheus.Gatherer
+
+	mu       sync.Mutex
+	snapshot []*dto.MetricFamily
+	expires  time.Time
+}
+
+// Gather returns the live metrics, completed with the handed off values of
+// metrics missing from them.
+func (w *warmGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := w.live.Gather()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.snapshot) == 0 {
+		return mfs, err
+	}
+	if time.Now().After(w.expires) {
+		w.snapshot = nil
+		return mfs, err
+	}
+
+	live := make(map[string]bool, len(mfs))
+	for _, mf := range mfs {
+		live[mf.GetName()] = true
+	}
+	var rest []*dto.MetricFamily
+	for _, mf := range w.snapshot {
+		if !live[mf.GetName()] {
+			mfs = append(mfs, mf)
+			rest = append(rest, mf)
+		}
+	}
+	// Once collected, a metric is never served from the snapshot again.
+	w.snapshot = rest
+
+	return mfs, err
+}
+
+// restore sets the handed off values, served until expires at the latest.
+func (w *warmGatherer) restore(exposition string, expires time.Time) error {
+	var p expfmt.TextParser
+	families, err := p.TextToMetricFamilies(strings.NewReader(exposition))
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.snapshot = w.snapshot[:0]
+	for _, mf := range families {
+		w.snapshot = append(w.snapshot, mf)
+	}
+	w.expires = expires
+	return nil
+}
+
+// receiveHandoff fetches the metric values of the process listening on the
+// handoff socket, if any, and restores them in the views. The previous
+// process exits once they are sent.
+func receiveHandoff(path string, vs []*view, expires time.Time) {
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		// No previous process, e.g. on first start.
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	var expositions map[string]string
+	if err := json.NewDecoder(conn).Decode(&expositions); err != nil {
+		log.Printf("handoff: %v", err)
+		return
+	}
+	restored := 0
+	for _, v := range vs {
+		exposition, ok := expositions[v.ViewID]
+		if !ok {
+			continue
+		}
+		if err := v.warm.restore(exposition, expires); err != nil {
+			log.Printf("handoff: view %s: %v", v.displayName(), err)
+			continue
+		}
+		restored++
+	}
+	log.Printf("handoff: restored %d views from the previous process", restored)
+}
+
+// serveHandoff listens on the handoff socket for the next process, sends it
+// the metric values of the views and exits, leaving it the port.
+func serveHandoff(path string) error {
+	// A previous process may still be listening, it is left the socket
+	// it holds while a new one is bound to the path.
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				log.Printf("handoff: %v", err)
+				return
+			}
+
+			vs := getViews()
+			expositions := make(map[string]string, len(vs))
+			for _, v := range vs {
+				mfs, err := v.registry.Gather()
+				if err != nil {
+					log.Printf("handoff: view %s: %v", v.displayName(), err)
+				}
+				var buf bytes.Buffer
+				for _, mf := range mfs {
+					expfmt.MetricFamilyToText(&buf, mf)
+				}
+				expositions[v.ViewID] = buf.String()
+			}
+			if err := json.NewEncoder(conn).Encode(expositions); err != nil {
+				log.Printf("handoff: %v", err)
+				conn.Close()
+				continue
+			}
+			conn.Close()
+
+			log.Printf("handoff: metric values sent to the next process, exiting")
+			os.Exit(0)
+		}
+	}()
+
+	return nil
+}
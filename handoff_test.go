@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// gatheredValue returns the value of the gauge name gathered from the view,
+// and whether it was gathered.
+func gatheredValue(t *testing.T, v *view, name string) (float64, bool) {
+	t.Helper()
+	mfs, err := v.warm.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == name && len(mf.Metric) == 1 {
+			return mf.Metric[0].GetGauge().GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+func TestHandoffServedUntilCollected(t *testing.T) {
+	tmpl, err := parseNameTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := newDryRunView(&viewConf{ViewID: "ga:1", Metrics: []string{"rt:activeUsers"}}, tmpl)
+	name, err := v.metricName("rt:activeUsers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exposition := "# TYPE " + name + " gauge\n" + name + "{view_id=\"ga:1\"} 42\n"
+	if err := v.warm.restore(exposition, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Before the first collection the handed off value is served, not 0.
+	if got, ok := gatheredValue(t, v, name); !ok || got != 42 {
+		t.Fatalf("before the first collection got %v (gathered %v), want 42", got, ok)
+	}
+
+	g := v.promGauge["rt:activeUsers"]
+	if err := v.registerGauge("rt:activeUsers", g); err != nil {
+		t.Fatal(err)
+	}
+	g.Set(7)
+	if got, ok := gatheredValue(t, v, name); !ok || got != 7 {
+		t.Fatalf("after the first collection got %v (gathered %v), want 7", got, ok)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+var readyChecks = struct {
+	sync.Mutex
+	m map[string]func() error
+}{m: make(map[string]func() error)}
+
+// addReadyCheck registers a check reported on /readyz, the exporter is ready
+// when all checks return nil.
+func addReadyCheck(name string, check func() error) {
+	readyChecks.Lock()
+	defer readyChecks.Unlock()
+	readyChecks.m[name] = check
+}
+
+// readyzHandler serves the state of the readiness checks.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	readyChecks.Lock()
+	names := make([]string, 0, len(readyChecks.m))
+	checks := make(map[string]func() error, len(readyChecks.m))
+	for name, check := range readyChecks.m {
+		names = append(names, name)
+		checks[name] = check
+	}
+	readyChecks.Unlock()
+	sort.Strings(names)
+
+	status := http.StatusOK
+	body := ""
+	for _, name := range names {
+		if err := checks[name](); err != nil {
+			status = http.StatusServiceUnavailable
+			body += fmt.Sprintf("%s: %v\n", name, err)
+		} else {
+			body += fmt.Sprintf("%s: ok\n", name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the label snapshots.
+const (
+	defaultLabelSnapshotInterval  = 3600
+	defaultLabelSnapshotRetention = 7 * 24 * 3600
+)
+
+// labelSnapshotConf keeps the label values observed per dimension with when
+// they were first and last seen, to analyze label churn, e.g. new page
+// paths per day, and tune relabeling.
+type labelSnapshotConf struct {
+	// Path of the JSON file the values are written to every interval and
+	// read back at startup. Without it they are only served on /labels.
+	Path string `yaml:"path"`
+	// Interval in seconds between writes. Defaults to an hour.
+	Interval int `yaml:"interval"`
+	// Retention in seconds of values no longer seen. Defaults to a week.
+	Retention int `yaml:"retention"`
+}
+
+// labelSeen is when a label value was first and last seen.
+type labelSeen struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// labelSnapshot is the label values of every view by label name.
+type labelSnapshot struct {
+	Time  time.Time                                   `json:"time"`
+	Views map[string]map[string]map[string]*labelSeen `json:"views"`
+}
+
+// labelSnapshots is set from label_snapshots.
+var labelSnapshots *labelTracker
+
+// labelTracker observes the label values of the metrics collected every
+// cycle, the registries only hold the current ones.
+type labelTracker struct {
+	conf *labelSnapshotConf
+
+	mu    sync.Mutex
+	views map[string]map[string]map[string]*labelSeen
+}
+
+// newLabelTracker returns a tracker with the values of the last snapshot
+// written, if any.
+func newLabelTracker(lc *labelSnapshotConf) (*labelTracker, error) {
+	t := &labelTracker{conf: lc, views: make(map[string]map[string]map[string]*labelSeen)}
+	if lc.Path == "" {
+		return t, nil
+	}
+	data, err := ioutil.ReadFile(lc.Path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s labelSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Views != nil {
+		t.views = s.Views
+	}
+	return t, nil
+}
+
+// observe records the label values of the metrics of views at ts, but the
+// labels of every metric of a view and the exporter's own.
+func (t *labelTracker) observe(ts time.Time, vs []*view) {
+	for _, v := range vs {
+		mfs, err := v.registry.Gather()
+		if err != nil {
+			log.Printf("label snapshots: view %s: %v", v.displayName(), err)
+		}
+		constLabels := v.constLabels()
+
+		t.mu.Lock()
+		labels, ok := t.views[v.ViewID]
+		if !ok {
+			labels = make(map[string]map[string]*labelSeen)
+			t.views[v.ViewID] = labels
+		}
+		for _, mf := range mfs {
+			if strings.HasPrefix(mf.GetName(), "ga_exporter_") {
+				continue
+			}
+			for _, m := range mf.Metric {
+				for _, lp := range m.Label {
+					if _, ok := constLabels[lp.GetName()]; ok {
+						continue
+					}
+					values, ok := labels[lp.GetName()]
+					if !ok {
+						values = make(map[string]*labelSeen)
+						labels[lp.GetName()] = values
+					}
+					seen, ok := values[lp.GetValue()]
+					if !ok {
+						seen = &labelSeen{FirstSeen: ts}
+						values[lp.GetValue()] = seen
+					}
+					seen.LastSeen = ts
+				}
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// snapshot returns a copy of the values first seen since, all of them when
+// since is zero, after dropping those not seen within the retention.
+func (t *labelTracker) snapshot(now, since time.Time) labelSnapshot {
+	expired := now.Add(-time.Duration(t.conf.Retention) * time.Second)
+	s := labelSnapshot{Time: now, Views: make(map[string]map[string]map[string]*labelSeen)}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for viewID, labels := range t.views {
+		sl := make(map[string]map[string]*labelSeen)
+		for name, values := range labels {
+			sv := make(map[string]*labelSeen)
+			for value, seen := range values {
+				if seen.LastSeen.Before(expired) {
+					delete(values, value)
+					continue
+				}
+				if !seen.FirstSeen.Before(since) {
+					c := *seen
+					sv[value] = &c
+				}
+			}
+			if len(values) == 0 {
+				delete(labels, name)
+			}
+			if len(sv) > 0 {
+				sl[name] = sv
+			}
+		}
+		s.Views[viewID] = sl
+	}
+	return s
+}
+
+// run writes a snapshot every interval, it never returns.
+func (t *labelTracker) run() {
+	for range time.Tick(time.Duration(t.conf.Interval) * time.Second) {
+		if err := t.write(time.Now()); err != nil {
+			log.Printf("label snapshots: %v", err)
+		}
+	}
+}
+
+// write replaces the snapshot file atomically.
+func (t *labelTracker) write(now time.Time) error {
+	data, err := json.Marshal(t.snapshot(now, time.Time{}))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(t.conf.Path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(t.conf.Path+".tmp", t.conf.Path)
+}
+
+// labelsHandler serves the label values observed as JSON, only those first
+// seen within the ?since= duration when set, e.g. 24h.
+func labelsHandler(w http.ResponseWriter, r *http.Request) {
+	if labelSnapshots == nil {
+		http.Error(w, "label_snapshots is not configured", http.StatusNotFound)
+		return
+	}
+	now := time.Now()
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+		since = now.Add(-d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(labelSnapshots.snapshot(now, since))
+}
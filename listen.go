@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// listen returns the listeners of the metrics port, on each of addrs, or
+// the sockets passed by systemd when socket activated. network is tcp, tcp4
+// or tcp6. With reusePort several processes can be bound to the port at
+// once, as during a handoff.
+func listen(network string, addrs []string, reusePort bool) ([]net.Listener, error) {
+	if ls, err := activationListeners(); ls != nil || err != nil {
+		return ls, err
+	}
+
+	lc := net.ListenConfig{}
+	if reusePort {
+		lc.Control = setReusePort
+	}
+	var ls []net.Listener
+	for _, addr := range addrs {
+		l, err := lc.Listen(context.Background(), network, addr)
+		if err != nil {
+			for _, l := range ls {
+				l.Close()
+			}
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// activationListeners returns the sockets passed by systemd socket
+// activation, nil when the exporter was not socket activated.
+func activationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+	// Not inherited by child processes, e.g. exec plugins.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	var ls []net.Listener
+	for fd := listenFDsStart; fd < listenFDsStart+fds; fd++ {
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("LISTEN_FD_%d", fd))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("socket activation: fd %d: %v", fd, err)
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
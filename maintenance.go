@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxMaintenanceDuration bounds the windows, the start of an open one is
+// looked up day by day back to their duration.
+const maxMaintenanceDuration = 7 * 24 * 3600
+
+// maintenanceConf is a recurring window during which nothing is collected,
+// e.g. while a GA property is reprocessed. Its start is a cron expression or
+// an RFC 5545 RRULE.
+type maintenanceConf struct {
+	// Cron is a 5 field cron expression, minute hour day-of-month month
+	// day-of-week, e.g. "0 2 * * sun". Unlike cron, a window starts when
+	// both the day of the month and of the week match.
+	Cron string `yaml:"cron"`
+	// RRule is an RFC 5545 recurrence rule with a DAILY, WEEKLY or
+	// MONTHLY frequency and BYMONTHDAY, BYDAY, BYHOUR and BYMINUTE
+	// parts, e.g. "FREQ=WEEKLY;BYDAY=SU;BYHOUR=2".
+	RRule string `yaml:"rrule"`
+	// Duration of the window in seconds.
+	Duration int `yaml:"duration"`
+	// Timezone of the start, e.g. Europe/Paris. Defaults to the local
+	// time zone.
+	Timezone string `yaml:"timezone"`
+}
+
+var inMaintenanceGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "ga_exporter_in_maintenance",
+	Help: "Whether a maintenance window is open, nothing is collected meanwhile.",
+})
+
+func init() {
+	prometheus.MustRegister(inMaintenanceGauge)
+}
+
+// cronSpec is the minutes, hours, days of the month, months and weekdays a
+// window starts on.
+type cronSpec struct {
+	minute, hour, dom, month, dow map[int]bool
+}
+
+// matches reports whether a window starts at the minute of t.
+func (s *cronSpec) matches(t time.Time) bool {
+	return s.minute[t.Minute()] && s.hour[t.Hour()] && s.dom[t.Day()] &&
+		s.month[int(t.Month())] && s.dow[int(t.Weekday())]
+}
+
+// prev returns the last start at or before t, looked up on the days back to
+// days before t, and false when there is none.
+func (s *cronSpec) prev(t time.Time, days int) (time.Time, bool) {
+	loc := t.Location()
+	for i := 0; i <= days; i++ {
+		// Dates are counted in UTC, in the time zone a date skipped by
+		// a change of the clocks would be normalized to another.
+		date := time.Date(t.Year(), t.Month(), t.Day()-i, 0, 0, 0, 0, time.UTC)
+		if !s.dom[date.Day()] || !s.month[int(date.Month())] || !s.dow[int(date.Weekday())] {
+			continue
+		}
+		// Noon, midnight is skipped when the clocks go forward in some
+		// time zones.
+		day := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, loc)
+		// The wall clock of a start is looked up at the offsets around
+		// the day, which differ when the clocks change that day: a
+		// start skipped when they go forward is at no offset, one
+		// repeated when they go back is at both.
+		var offsets []int
+		for _, d := range []time.Duration{-14 * time.Hour, 0, 14 * time.Hour} {
+			_, offset := day.Add(d).Zone()
+			if len(offsets) == 0 || offsets[len(offsets)-1] != offset {
+				offsets = append(offsets, offset)
+			}
+		}
+
+		// Minutes are looked up backwards, the first start found is
+		// the last unless the clocks changed: starts of up to two
+		// hours of wall clock before may be later.
+		// On the date of t, starts before it are at most two hours of
+		// wall clock after it, when the clocks went back.
+		last := 23
+		if i == 0 && t.Hour()+2 < last {
+			last = t.Hour() + 2
+		}
+		var start time.Time
+		found := -1
+		for h := last; h >= 0; h-- {
+			if !s.hour[h] {
+				continue
+			}
+			for m := 59; m >= 0; m-- {
+				if !s.minute[m] {
+					continue
+				}
+				if found >= 0 && (len(offsets) == 1 || h*60+m < found-120) {
+					return start, true
+				}
+				for _, offset := range offsets {
+					c := time.Date(date.Year(), date.Month(), date.Day(), h, m, 0, 0, time.FixedZone("", offset)).In(loc)
+					if c.Day() != date.Day() || c.Hour() != h || c.Minute() != m || c.After(t) || !c.After(start) {
+						continue
+					}
+					start = c
+					if found < 0 {
+						found = h*60 + m
+					}
+				}
+			}
+		}
+		if found >= 0 {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// maintenanceWindow is a compiled maintenanceConf.
+type maintenanceWindow struct {
+	start    *cronSpec
+	duration time.Duration
+	loc      *time.Location
+}
+
+// open reports whether the window is open at t: its last start is within
+// its duration before.
+func (w *maintenanceWindow) open(t time.Time) bool {
+	t = t.In(w.loc).Truncate(time.Minute)
+	// A day more for dates skipped by a change of the clocks.
+	start, ok := w.start.prev(t, int(w.duration/(24*time.Hour))+2)
+	return ok && t.Sub(start) < w.duration
+}
+
+// compile parses the start of the window.
+func (mc *maintenanceConf) compile() (*maintenanceWindow, error) {
+	if mc.Duration <= 0 || mc.Duration > maxMaintenanceDuration {
+		return nil, fmt.Errorf("duration must be between 1 and %d seconds", maxMaintenanceDuration)
+	}
+	loc := time.Local
+	if mc.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(mc.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	var spec *cronSpec
+	var err error
+	switch {
+	case mc.Cron != "" && mc.RRule != "":
+		return nil, errors.New("cron and rrule are exclusive")
+	case mc.Cron != "":
+		spec, err = parseCron(mc.Cron)
+	case mc.RRule != "":
+		spec, err = parseRRule(mc.RRule)
+	default:
+		return nil, errors.New("cron or rrule is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &maintenanceWindow{start: spec, duration: time.Duration(mc.Duration) * time.Second, loc: loc}, nil
+}
+
+var (
+	cronMonths = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	cronDays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// parseCron parses a 5 field cron expression. Fields are *, values, a-b
+// ranges and lists of them, with an optional /step; months and weekdays
+// can be names, 7 is Sunday too.
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields", expr)
+	}
+	var s cronSpec
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron %q: minute: %v", expr, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron %q: hour: %v", expr, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron %q: day of month: %v", expr, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("cron %q: month: %v", expr, err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDays); err != nil {
+		return nil, fmt.Errorf("cron %q: day of week: %v", expr, err)
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	return &s, nil
+}
+
+// parseCronField parses a cron field into the set of its values.
+func parseCronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToLower(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("invalid value %q", s)
+		}
+		return n, nil
+	}
+
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			part = part[:i]
+		}
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = value(bounds[0]); err != nil {
+				return nil, err
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = value(bounds[1]); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				to = max
+			}
+			if from > to {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		for n := from; n <= to; n += step {
+			set[n] = true
+		}
+	}
+	return set, nil
+}
+
+// parseRRule parses the subset of RFC 5545 recurrence rules a cron
+// expression can express.
+func parseRRule(rule string) (*cronSpec, error) {
+	parts := make(map[string]string)
+	for _, p := range strings.Split(strings.TrimPrefix(strings.ToUpper(rule), "RRULE:"), ";") {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("rrule %q: invalid part %q", rule, p)
+		}
+		parts[kv[0]] = kv[1]
+	}
+
+	field := func(name, def string) string {
+		if v, ok := parts[name]; ok {
+			delete(parts, name)
+			return v
+		}
+		return def
+	}
+	freq := field("FREQ", "")
+	minute, hour := field("BYMINUTE", "0"), field("BYHOUR", "0")
+	dom, dow := field("BYMONTHDAY", "*"), field("BYDAY", "*")
+	if i := field("INTERVAL", "1"); i != "1" {
+		return nil, fmt.Errorf("rrule %q: INTERVAL is not supported", rule)
+	}
+	for name := range parts {
+		return nil, fmt.Errorf("rrule %q: %s is not supported", rule, name)
+	}
+	switch freq {
+	case "DAILY":
+		if dom != "*" || dow != "*" {
+			return nil, fmt.Errorf("rrule %q: DAILY takes no BYMONTHDAY nor BYDAY", rule)
+		}
+	case "WEEKLY":
+		if dom != "*" || dow == "*" {
+			return nil, fmt.Errorf("rrule %q: WEEKLY requires BYDAY and takes no BYMONTHDAY", rule)
+		}
+	case "MONTHLY":
+		if dom == "*" || dow != "*" {
+			return nil, fmt.Errorf("rrule %q: MONTHLY requires BYMONTHDAY and takes no BYDAY", rule)
+		}
+	default:
+		return nil, fmt.Errorf("rrule %q: FREQ must be DAILY, WEEKLY or MONTHLY", rule)
+	}
+
+	days := strings.Split(dow, ",")
+	for i, d := range days {
+		if d != "*" {
+			if len(d) != 2 {
+				return nil, fmt.Errorf("rrule %q: invalid BYDAY %q", rule, d)
+			}
+			days[i] = map[string]string{"SU": "sun", "MO": "mon", "TU": "tue", "WE": "wed", "TH": "thu", "FR": "fri", "SA": "sat"}[d]
+		}
+	}
+	return parseCron(strings.Join([]string{minute, hour, dom, "*", strings.Join(days, ",")}, " "))
+}
+
+// maintenance tracks whether a maintenance window is open.
+type maintenance struct {
+	windows []*maintenanceWindow
+
+	mu   sync.Mutex
+	last bool
+}
+
+// maintenanceWindows are compiled from maintenance_windows.
+var maintenanceWindows *maintenance
+
+func newMaintenance(mcs []*maintenanceConf) (*maintenance, error) {
+	m := new(maintenance)
+	for i, mc := range mcs {
+		w, err := mc.compile()
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window #%d: %v", i+1, err)
+		}
+		m.windows = append(m.windows, w)
+	}
+	return m, nil
+}
+
+// active reports whether a window is open at t, logging when one opens or
+// closes. A nil maintenance has no window.
+func (m *maintenance) active(t time.Time) bool {
+	if m == nil {
+		return false
+	}
+	open := false
+	for _, w := range m.windows {
+		if w.open(t) {
+			open = true
+			break
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if open != m.last {
+		if open {
+			log.Printf("maintenance window open, collection suspended")
+		} else {
+			log.Printf("maintenance window closed, collection resumed")
+		}
+		m.last = open
+	}
+	if open {
+		inMaintenanceGauge.Set(1)
+	} else {
+		inMaintenanceGauge.Set(0)
+	}
+	return open
+}
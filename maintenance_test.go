@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMaintenanceOpen compares open with a minute by minute scan of the
+// window, around changes of the clocks.
+func TestMaintenanceOpen(t *testing.T) {
+	scan := func(w *maintenanceWindow, t time.Time) bool {
+		t = t.In(w.loc).Truncate(time.Minute)
+		for d := time.Duration(0); d < w.duration; d += time.Minute {
+			if w.start.matches(t.Add(-d)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		timezone string
+		from     time.Time
+	}{
+		{"Europe/Paris", time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)},
+		{"America/New_York", time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)},
+		// The clocks go back 30 minutes.
+		{"Australia/Lord_Howe", time.Date(2024, 4, 6, 0, 0, 0, 0, time.UTC)},
+		// Midnight is skipped.
+		{"America/Santiago", time.Date(2024, 9, 7, 12, 0, 0, 0, time.UTC)},
+		// December 30 2011 is skipped.
+		{"Pacific/Apia", time.Date(2011, 12, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		for _, cron := range []string{"30 1-3 * * *", "0 0 * * *", "50 23 * * *", "*/20 * * * *"} {
+			for _, duration := range []int{1800, 5400, 86400} {
+				mc := &maintenanceConf{Cron: cron, Duration: duration, Timezone: tt.timezone}
+				w, err := mc.compile()
+				if err != nil {
+					t.Fatal(err)
+				}
+				for at := tt.from; at.Before(tt.from.Add(48 * time.Hour)); at = at.Add(7 * time.Minute) {
+					if got, want := w.open(at), scan(w, at); got != want {
+						t.Errorf("%s %q for %ds at %v: open %v, want %v", tt.timezone, cron, duration, at.In(w.loc), got, want)
+					}
+				}
+			}
+		}
+	}
+}
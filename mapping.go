@@ -0,0 +1,389 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+)
+
+// mapping declares how the columns of a query response become samples.
+// Columns are referenced by name, so adding or reordering dimensions does
+// not shift values into the wrong series.
+type mapping struct {
+	// Labels maps dimension columns to the label names they become.
+	Labels map[string]string `yaml:"labels"`
+	// Values lists the metric columns exported, all of them when empty.
+	Values []string `yaml:"values"`
+	// Ignore lists columns dropped from the samples.
+	Ignore []string `yaml:"ignore"`
+	// NameFrom is a column whose value names the metric, as done for
+	// event actions without a mapping.
+	NameFrom string `yaml:"name_from"`
+	// FoldCase lowercases label values, GA sometimes returns the same page
+	// path with different capitalization.
+	FoldCase bool `yaml:"fold_case"`
+	// Merge combines the values of rows ending up with the same labels,
+	// sum, max or avg. Defaults to sum.
+	Merge string `yaml:"merge"`
+
+	// suffix is appended to the names exported, of breakdowns.
+	suffix string
+}
+
+// merges are the supported ways of combining values of rows with the same
+// labels.
+var merges = map[string]bool{"sum": true, "max": true, "avg": true}
+
+// sample is a value to export, merged from one or more rows.
+type sample struct {
+	name        string
+	labelValues []string
+	value       float64
+	n           int
+}
+
+// add merges a row value into the sample.
+func (s *sample) add(merge string, valf float64) {
+	s.n++
+	switch {
+	case s.n == 1:
+		s.value = valf
+	case merge == "max":
+		s.value = math.Max(s.value, valf)
+	default:
+		s.value += valf
+	}
+}
+
+// result returns the merged value.
+func (s *sample) result(merge string) float64 {
+	if merge == "avg" && s.n > 0 {
+		return s.value / float64(s.n)
+	}
+	return s.value
+}
+
+// defaultMapping exports every metric column, labeled by all dimension
+// columns named after the dimension, e.g. rt:deviceCategory as deviceCategory.
+func defaultMapping(headers []*analytics.RealtimeDataColumnHeaders) *mapping {
+	mp := &mapping{Labels: make(map[string]string)}
+	for _, h := range headers {
+		if h.ColumnType == "DIMENSION" {
+			mp.Labels[h.Name] = dimensionLabel(h.Name)
+		}
+	}
+
+	return mp
+}
+
+// dimensionLabel returns a label name for a dimension column.
+func dimensionLabel(column string) string {
+	if i := strings.Index(column, ":"); i >= 0 {
+		column = column[i+1:]
+	}
+	return invalidNameChars.ReplaceAllString(column, "_")
+}
+
+// exportMapped sets the gauges of a metric, or breakdown, from a query
+// response.
+func (v *view) exportMapped(metric string, m *analytics.RealtimeData, mp *mapping) error {
+	// The settings of a metric apply to its breakdowns.
+	base := v.baseMetric(metric)
+	var labelNames []string
+	var labelCols, valueCols []int
+	nameCol := -1
+
+	ignored := make(map[string]bool)
+	for _, c := range mp.Ignore {
+		ignored[c] = true
+	}
+	values := make(map[string]bool)
+	for _, c := range mp.Values {
+		values[c] = true
+	}
+
+	for i, h := range m.ColumnHeaders {
+		switch {
+		case ignored[h.Name]:
+		case h.Name == mp.NameFrom:
+			nameCol = i
+		case mp.Labels[h.Name] != "":
+			labelNames = append(labelNames, mp.Labels[h.Name])
+			labelCols = append(labelCols, i)
+		case h.ColumnType == "METRIC" && (len(values) == 0 || values[h.Name]):
+			valueCols = append(valueCols, i)
+		case h.ColumnType == "METRIC":
+		default:
+			return fmt.Errorf("column %s is not mapped", h.Name)
+		}
+	}
+	if nameCol >= 0 && len(valueCols) > 1 {
+		return fmt.Errorf("name_from requires a single value column")
+	}
+
+	// In strict mode only declared columns are exported, rows of anything
+	// else are counted.
+	var declared map[string]bool
+	if config.StrictMetrics {
+		declared = v.declaredColumns(metric)
+		for _, col := range labelCols {
+			if !declared[m.ColumnHeaders[col].Name] {
+				v.unexpectedRows.WithLabelValues(metric).Add(float64(len(m.Rows)))
+				return nil
+			}
+		}
+	}
+
+	// Without dimensions no rows are returned when there was no activity,
+	// the totals still hold the value.
+	if len(labelCols) == 0 && nameCol < 0 && len(m.Rows) == 0 && mp.suffix == "" {
+		for _, col := range valueCols {
+			name := m.ColumnHeaders[col].Name
+			if g, ok := v.promGauge[name]; ok {
+				if err := v.registerGauge(name, g); err != nil {
+					return err
+				}
+				valf, _ := strconv.ParseFloat(m.TotalsForAllResults[name], 64)
+				if err := v.set(g, base, name, nil, nil, valf); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// Dimensioned metrics also get their total, sparing a sum() over the
+	// breakdown in dashboards.
+	if len(labelCols) > 0 || nameCol >= 0 {
+		for _, col := range valueCols {
+			name := m.ColumnHeaders[col].Name
+			total, ok := m.TotalsForAllResults[name]
+			if !ok {
+				continue
+			}
+			g, err := v.registerTotal(name + mp.suffix)
+			if err != nil {
+				return err
+			}
+			valf, _ := strconv.ParseFloat(total, 64)
+			g.Set(v.guard(base, name+mp.suffix+"_all", nil, nil, v.Precision[base].round(valf)))
+		}
+	}
+
+	dropped := make(map[string]float64)
+	for _, col := range valueCols {
+		dropped[m.ColumnHeaders[col].Name] = 0
+	}
+
+	var samples []*sample
+	seen := make(map[string]*sample)
+
+	columns := make([]string, len(m.ColumnHeaders))
+	for i, h := range m.ColumnHeaders {
+		columns[i] = h.Name
+	}
+
+	for _, row := range m.Rows {
+		if c := v.filterRow(columns, row); c != "" {
+			v.filteredRows.WithLabelValues(metric, c).Inc()
+			for _, col := range valueCols {
+				valf, _ := strconv.ParseFloat(row[col], 64)
+				dropped[m.ColumnHeaders[col].Name] += valf
+			}
+			continue
+		}
+
+		labelValues := make([]string, len(labelCols))
+		skip := false
+		for i, col := range labelCols {
+			labelValues[i] = sanitizeLabelValue(row[col])
+			if mp.FoldCase {
+				labelValues[i] = strings.ToLower(labelValues[i])
+			}
+			skip = skip || strings.Contains(row[col], "(not set)")
+		}
+		if skip && !v.IncludeNotSet {
+			for _, col := range valueCols {
+				valf, _ := strconv.ParseFloat(row[col], 64)
+				dropped[m.ColumnHeaders[col].Name] += valf
+			}
+			continue
+		}
+
+		for _, col := range valueCols {
+			name := m.ColumnHeaders[col].Name
+			if nameCol >= 0 {
+				name = buildMetricLabel(row[nameCol])
+			}
+			valf, _ := strconv.ParseFloat(row[col], 64)
+
+			key := name + "\xff" + strings.Join(labelValues, "\xff")
+			s, ok := seen[key]
+			if !ok {
+				s = &sample{name: name, labelValues: labelValues}
+				seen[key] = s
+				samples = append(samples, s)
+			}
+			s.add(mp.Merge, valf)
+		}
+	}
+
+	if len(v.ExpectedValues) > 0 {
+		labelColumns := make([]string, len(labelCols))
+		for i, col := range labelCols {
+			labelColumns[i] = m.ColumnHeaders[col].Name
+		}
+		var names []string
+		if nameCol < 0 {
+			for _, col := range valueCols {
+				names = append(names, m.ColumnHeaders[col].Name)
+			}
+		}
+		samples = v.zeroFill(samples, labelColumns, names)
+	}
+
+	for _, s := range samples {
+		if declared != nil && !declared[s.name] {
+			v.unexpectedRows.WithLabelValues(metric).Inc()
+			continue
+		}
+		if g, ok := v.promGauge[s.name]; ok && len(labelCols) == 0 && mp.suffix == "" {
+			if err := v.registerGauge(s.name, g); err != nil {
+				return err
+			}
+			if err := v.set(g, base, s.name, nil, nil, s.result(mp.Merge)); err != nil {
+				return err
+			}
+			continue
+		}
+		name := s.name + mp.suffix
+		if !v.admitSeries(name, s.labelValues) {
+			continue
+		}
+		vec, err := v.registerMetricVec(name, labelNames)
+		if err != nil {
+			return err
+		}
+		g, err := vec.GetMetricWithLabelValues(s.labelValues...)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := v.set(g, base, name, labelNames, s.labelValues, s.result(mp.Merge)); err != nil {
+			return err
+		}
+	}
+
+	if v.droppedValue != nil {
+		for name, valf := range dropped {
+			v.droppedValue.WithLabelValues(name + mp.suffix).Set(valf)
+		}
+	}
+
+	return nil
+}
+
+// set sets a gauge of the series name and labels of metric, rounded to the
+// metric precision and within its bounds, scores it against its baseline
+// and tracks its change.
+func (v *view) set(g prometheus.Gauge, metric, name string, labelNames, labelValues []string, valf float64) error {
+	valf = v.Precision[metric].round(valf)
+	valf = v.guard(metric, name, labelNames, labelValues, valf)
+	g.Set(valf)
+	if err := v.observe(metric, name, labelNames, labelValues, valf); err != nil {
+		return err
+	}
+	return v.trackChange(metric, name, labelNames, labelValues, valf)
+}
+
+// zeroFill adds the expected label values of the view missing from samples
+// with a 0 value, so alerts on these series don't break when GA returns no
+// row for them. Values of label columns without expectations are taken from
+// the samples, when all have expectations series are also added for names,
+// the metrics exported without any row.
+func (v *view) zeroFill(samples []*sample, labelColumns, names []string) []*sample {
+	var expected []int
+	for i, c := range labelColumns {
+		if len(v.ExpectedValues[c]) > 0 {
+			expected = append(expected, i)
+		}
+	}
+	if len(expected) == 0 {
+		return samples
+	}
+
+	seen := make(map[string]bool)
+	groups := make(map[string]*sample)
+	var order []string
+	addGroup := func(name string, labelValues []string) {
+		base := make([]string, len(labelValues))
+		copy(base, labelValues)
+		for _, i := range expected {
+			base[i] = ""
+		}
+		key := name + "\xff" + strings.Join(base, "\xff")
+		if _, ok := groups[key]; !ok {
+			groups[key] = &sample{name: name, labelValues: base}
+			order = append(order, key)
+		}
+	}
+	for _, s := range samples {
+		seen[s.name+"\xff"+strings.Join(s.labelValues, "\xff")] = true
+		addGroup(s.name, s.labelValues)
+	}
+	if len(expected) == len(labelColumns) {
+		for _, name := range names {
+			addGroup(name, make([]string, len(labelColumns)))
+		}
+	}
+
+	for _, key := range order {
+		g := groups[key]
+		// Iterate over the product of the expected values, as an odometer
+		// over the expected columns.
+		idx := make([]int, len(expected))
+		for {
+			labelValues := make([]string, len(g.labelValues))
+			copy(labelValues, g.labelValues)
+			for j, i := range expected {
+				labelValues[i] = v.ExpectedValues[labelColumns[i]][idx[j]]
+			}
+			if k := g.name + "\xff" + strings.Join(labelValues, "\xff"); !seen[k] {
+				seen[k] = true
+				samples = append(samples, &sample{name: g.name, labelValues: labelValues})
+			}
+
+			j := 0
+			for ; j < len(expected); j++ {
+				idx[j]++
+				if idx[j] < len(v.ExpectedValues[labelColumns[expected[j]]]) {
+					break
+				}
+				idx[j] = 0
+			}
+			if j == len(expected) {
+				break
+			}
+		}
+	}
+
+	return samples
+}
+
+// declaredColumns returns the metric and dimension columns a metric query,
+// or breakdown, of the view is configured with.
+func (v *view) declaredColumns(metric string) map[string]bool {
+	declared := make(map[string]bool)
+	for _, c := range strings.Split(v.baseMetric(metric), ",") {
+		declared[c] = true
+	}
+	if dimensions := v.getDimensions(metric); dimensions != "" {
+		for _, c := range strings.Split(dimensions, ",") {
+			declared[c] = true
+		}
+	}
+	return declared
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/analytics/v3"
+	"google.golang.org/api/analyticsadmin/v1beta"
+	"gopkg.in/yaml.v2"
+)
+
+// ga4Equivalents are the GA4 realtime metrics and dimensions closest to
+// RealTime API ones, "" when there is none.
+var ga4Equivalents = map[string]string{
+	"rt:activeUsers":        "activeUsers",
+	"rt:pageviews":          "screenPageViews",
+	"rt:screenViews":        "screenPageViews",
+	"rt:totalEvents":        "eventCount",
+	"rt:goalCompletionsAll": "conversions",
+	"rt:deviceCategory":     "deviceCategory",
+	"rt:country":            "country",
+	"rt:city":               "city",
+	"rt:minutesAgo":         "minutesAgo",
+	"rt:eventAction":        "eventName",
+	"rt:eventCategory":      "eventName",
+	"rt:pageTitle":          "unifiedScreenName",
+	"rt:screenName":         "unifiedScreenName",
+	"rt:appVersion":         "appVersion",
+	"rt:platform":           "platform",
+	"rt:pagePath":           "",
+	"rt:source":             "",
+	"rt:medium":             "",
+	"rt:campaign":           "",
+	"rt:trafficType":        "",
+}
+
+// ga4Property is a GA4 property accessible with the credentials.
+type ga4Property struct {
+	id, name, accountID string
+}
+
+// migrate writes the config with a ga4_trend section for the GA4 properties
+// of the configured views, and reports the GA4 equivalents of their
+// metrics and dimensions. UA views are kept, they are collected until the
+// RealTime API is shut down. It returns the exit status.
+func migrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	out := fs.String("o", "", "File the converted config is written to, stdout when empty.")
+	overrides := fs.String("properties", "", "Comma separated viewid=property pairs, for views whose property isn't found.")
+	fs.Parse(args)
+
+	httpClient := newHTTPClient()
+	as, err := analytics.New(httpClient)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	profiles, err := listProfiles(as)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: listing views: %v\n", err)
+		return 1
+	}
+	admin, err := analyticsadmin.New(httpClient)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	properties, err := listGA4Properties(admin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: listing GA4 properties: %v\n", err)
+		return 1
+	}
+
+	byView := make(map[string]string)
+	for _, pair := range strings.Split(*overrides, ",") {
+		if kv := strings.SplitN(strings.TrimSpace(pair), "=", 2); len(kv) == 2 {
+			byView["ga:"+strings.TrimPrefix(kv[0], "ga:")] = strings.TrimPrefix(kv[1], "properties/")
+		}
+	}
+	byID := make(map[string]profile)
+	for _, p := range profiles {
+		byID["ga:"+p.id] = p
+	}
+
+	ids := make(map[string]bool)
+	metrics := make(map[string]bool)
+	unresolved := 0
+	for _, vc := range config.Views {
+		id, ok := byView[vc.ViewID]
+		if !ok {
+			p, ok := byID[vc.ViewID]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "%s: view not accessible\n", vc.ViewID)
+				unresolved++
+				continue
+			}
+			if id = linkedProperty(p, properties); id == "" {
+				fmt.Fprintf(os.Stderr, "%s: no GA4 property found for %s / %s, set it with -properties\n", vc.ViewID, p.account, p.property)
+				unresolved++
+				continue
+			}
+		}
+		ids[id] = true
+		fmt.Fprintf(os.Stderr, "%s: GA4 property %s\n", vc.ViewID, id)
+
+		for _, m := range vc.queries() {
+			for _, column := range strings.Split(vc.baseMetric(m), ",") {
+				if eq := ga4Equivalents[column]; eq != "" {
+					metrics[eq] = true
+				}
+				fmt.Fprintf(os.Stderr, "  metric %s\n", suggestion(column))
+			}
+			if d := vc.getDimensions(m); d != "" {
+				for _, column := range strings.Split(d, ",") {
+					fmt.Fprintf(os.Stderr, "  dimension %s\n", suggestion(column))
+				}
+			}
+		}
+	}
+
+	// The config is rewritten from its file, keeping the order of its
+	// settings.
+	data, err := ioutil.ReadFile(conffile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	var c yaml.MapSlice
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	trend := &ga4TrendConf{Properties: sortedKeys(ids), Metrics: sortedKeys(metrics), Minutes: 30}
+	for i, item := range c {
+		if item.Key == "ga4_trend" {
+			c = append(c[:i], c[i+1:]...)
+			break
+		}
+	}
+	c = append(c, yaml.MapItem{Key: "ga4_trend", Value: trend})
+	if data, err = yaml.Marshal(c); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+
+	if *out == "" {
+		os.Stdout.Write(data)
+	} else if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	} else {
+		fmt.Fprintf(os.Stderr, "config written to %s\n", *out)
+	}
+	if unresolved > 0 {
+		return 1
+	}
+	return 0
+}
+
+// listGA4Properties returns the GA4 properties accessible with the
+// credentials.
+func listGA4Properties(admin *analyticsadmin.Service) ([]ga4Property, error) {
+	var properties []ga4Property
+	token := ""
+	for {
+		resp, err := admin.AccountSummaries.List().PageSize(200).PageToken(token).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range resp.AccountSummaries {
+			for _, p := range a.PropertySummaries {
+				properties = append(properties, ga4Property{
+					id:        strings.TrimPrefix(p.Property, "properties/"),
+					name:      p.DisplayName,
+					accountID: strings.TrimPrefix(a.Account, "accounts/"),
+				})
+			}
+		}
+		if token = resp.NextPageToken; token == "" {
+			return properties, nil
+		}
+	}
+}
+
+// linkedProperty returns the GA4 property of a view: the only one of its
+// account, or the one named after its UA property, as done by the GA4 setup
+// assistant. "" when ambiguous or none.
+func linkedProperty(p profile, properties []ga4Property) string {
+	var candidates []ga4Property
+	for _, gp := range properties {
+		if gp.accountID == p.accountID {
+			candidates = append(candidates, gp)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0].id
+	}
+	found := ""
+	for _, gp := range candidates {
+		name := strings.TrimSpace(strings.TrimSuffix(gp.name, "- GA4"))
+		if strings.EqualFold(name, p.property) {
+			if found != "" {
+				return ""
+			}
+			found = gp.id
+		}
+	}
+	return found
+}
+
+// suggestion describes the GA4 equivalent of a RealTime column.
+func suggestion(column string) string {
+	eq, ok := ga4Equivalents[column]
+	switch {
+	case !ok:
+		return column + " -> unknown, check the GA4 realtime schema"
+	case eq == "":
+		return column + " -> no GA4 realtime equivalent"
+	}
+	return column + " -> " + eq
+}
+
+// sortedKeys returns the keys of a set, sorted.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
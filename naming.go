@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// defaultNameTemplate keeps the historical ga_<metric> naming.
+const defaultNameTemplate = `ga_{{ .Metric | sanitize }}`
+
+var (
+	nameTemplate *template.Template
+
+	invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+	nameFuncs = template.FuncMap{
+		"sanitize": func(s string) string {
+			return invalidNameChars.ReplaceAllString(strings.Replace(s, ":", "_", -1), "_")
+		},
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"trimPrefix": func(prefix, s string) string {
+			return strings.TrimPrefix(s, prefix)
+		},
+	}
+)
+
+// nameData is the data available to the metric naming template.
+type nameData struct {
+	View   *viewConf
+	Metric string
+}
+
+// parseNameTemplate parses the configured naming template and checks it
+// renders a valid metric name.
+func parseNameTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultNameTemplate
+	}
+
+	t, err := template.New("name").Funcs(nameFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := renderName(t, &nameData{View: &viewConf{ViewID: "ga:1", Alias: "alias"}, Metric: "rt:activeUsers"}); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// renderName executes the naming template.
+func renderName(t *template.Template, data *nameData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	name := buf.String()
+	if !metricName.MatchString(name) {
+		return "", fmt.Errorf("invalid metric name %q", name)
+	}
+
+	return name, nil
+}
+
+// metricName returns the Prometheus name of a GA metric of the view. The
+// names of the configured metrics are checked with the config, those of
+// name columns only once seen.
+func (v *view) metricName(metric string) (string, error) {
+	name, err := renderName(v.nameTemplate, &nameData{View: v.viewConf, Metric: metric})
+	if err != nil {
+		return "", fmt.Errorf("%s: metric_name_template: %v", metric, err)
+	}
+	return name, nil
+}
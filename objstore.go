@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/storage/v1"
+)
+
+// objectStore is where files written by the exporter are kept, a local
+// directory or a Cloud Storage or S3 bucket.
+type objectStore interface {
+	// exists reports whether the object of a key was written.
+	exists(key string) (bool, error)
+	// put writes the object of a key.
+	put(key string, data []byte, contentType string) error
+}
+
+// newObjectStore returns the store of a destination, a gs://bucket/prefix
+// or s3://bucket/prefix URL or a local directory. Cloud Storage is accessed
+// with the Google credentials of httpClient.
+func newObjectStore(httpClient *http.Client, destination string) (objectStore, error) {
+	if u, err := url.Parse(destination); err == nil && (u.Scheme == "gs" || u.Scheme == "s3") {
+		bucket, prefix := u.Host, strings.Trim(u.Path, "/")
+		if u.Scheme == "s3" {
+			tr, err := config.newTransport()
+			if err != nil {
+				return nil, err
+			}
+			return newS3Store(&http.Client{Transport: tr, Timeout: time.Minute}, bucket, prefix)
+		}
+		svc, err := storage.New(httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return &gcsStore{svc: svc, bucket: bucket, prefix: prefix}, nil
+	}
+
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return nil, err
+	}
+	return dirStore(destination), nil
+}
+
+// dirStore keeps objects as files of a directory.
+type dirStore string
+
+func (d dirStore) exists(key string) (bool, error) {
+	_, err := os.Stat(filepath.Join(string(d), filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// put writes the file atomically, a partial file is never taken as
+// written.
+func (d dirStore) put(key string, data []byte, contentType string) error {
+	name := filepath.Join(string(d), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(name+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// gcsStore keeps objects in a Google Cloud Storage bucket.
+type gcsStore struct {
+	svc    *storage.Service
+	bucket string
+	prefix string
+}
+
+func (g *gcsStore) exists(key string) (bool, error) {
+	_, err := g.svc.Objects.Get(g.bucket, path.Join(g.prefix, key)).Do()
+	if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (g *gcsStore) put(key string, data []byte, contentType string) error {
+	obj := &storage.Object{Name: path.Join(g.prefix, key), ContentType: contentType}
+	_, err := g.svc.Objects.Insert(g.bucket, obj).Media(bytes.NewReader(data), googleapi.ContentType(contentType)).Do()
+	return err
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Parquet physical and converted types of the snapshot columns.
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetNoConverted = -1
+	parquetUTF8        = 0
+	parquetDate        = 6
+)
+
+// parquetColumn is a required column of a Parquet file, its values PLAIN
+// encoded.
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+	values    bytes.Buffer
+}
+
+// parquetWriter writes a table as a Parquet file of a single row group,
+// uncompressed and PLAIN encoded, which is all the snapshots need and any
+// reader supports.
+type parquetWriter struct {
+	columns []*parquetColumn
+	rows    int64
+}
+
+// stringColumn, int64Column, doubleColumn and dateColumn add a column.
+func (w *parquetWriter) stringColumn(name string) {
+	w.columns = append(w.columns, &parquetColumn{name: name, typ: parquetByteArray, converted: parquetUTF8})
+}
+
+func (w *parquetWriter) int64Column(name string) {
+	w.columns = append(w.columns, &parquetColumn{name: name, typ: parquetInt64, converted: parquetNoConverted})
+}
+
+func (w *parquetWriter) doubleColumn(name string) {
+	w.columns = append(w.columns, &parquetColumn{name: name, typ: parquetDouble, converted: parquetNoConverted})
+}
+
+func (w *parquetWriter) dateColumn(name string) {
+	w.columns = append(w.columns, &parquetColumn{name: name, typ: parquetInt32, converted: parquetDate})
+}
+
+// write adds a row, its values as in the CSV files: dates as YYYY-MM-DD.
+func (w *parquetWriter) write(record []string) error {
+	if len(record) != len(w.columns) {
+		return fmt.Errorf("parquet: %d values for %d columns", len(record), len(w.columns))
+	}
+	for i, c := range w.columns {
+		v := record[i]
+		switch {
+		case c.converted == parquetDate:
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return fmt.Errorf("parquet: %s: %v", c.name, err)
+			}
+			binary.Write(&c.values, binary.LittleEndian, int32(t.Unix()/86400))
+		case c.typ == parquetInt64:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parquet: %s: %v", c.name, err)
+			}
+			binary.Write(&c.values, binary.LittleEndian, n)
+		case c.typ == parquetDouble:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("parquet: %s: %v", c.name, err)
+			}
+			binary.Write(&c.values, binary.LittleEndian, math.Float64bits(f))
+		default:
+			binary.Write(&c.values, binary.LittleEndian, uint32(len(v)))
+			c.values.WriteString(v)
+		}
+	}
+	w.rows++
+	return nil
+}
+
+// bytes returns the Parquet file: the magic number, a data page per column,
+// the file metadata, its length and the magic number again.
+func (w *parquetWriter) bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("PAR1")
+
+	// The offset and size of the chunk of each column.
+	offsets := make([]int64, len(w.columns))
+	sizes := make([]int64, len(w.columns))
+	if w.rows > 0 {
+		for i, c := range w.columns {
+			offsets[i] = int64(buf.Len())
+			h := new(thriftWriter)
+			h.begin()
+			h.i32(1, 0) // DATA_PAGE
+			h.i32(2, int32(c.values.Len()))
+			h.i32(3, int32(c.values.Len()))
+			h.structField(5)
+			h.i32(1, int32(w.rows))
+			h.i32(2, 0) // PLAIN
+			h.i32(3, 3) // RLE, no levels are written for required columns
+			h.i32(4, 3)
+			h.end()
+			h.end()
+			buf.Write(h.Bytes())
+			buf.Write(c.values.Bytes())
+			sizes[i] = int64(buf.Len()) - offsets[i]
+		}
+	}
+
+	m := new(thriftWriter)
+	m.begin()
+	m.i32(1, 1)
+	m.list(2, thriftStruct, len(w.columns)+1)
+	m.begin()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(w.columns)))
+	m.end()
+	for _, c := range w.columns {
+		m.begin()
+		m.i32(1, c.typ)
+		m.i32(3, 0) // REQUIRED
+		m.binary(4, c.name)
+		if c.converted != parquetNoConverted {
+			m.i32(6, c.converted)
+		}
+		m.end()
+	}
+	m.i64(3, w.rows)
+	if w.rows > 0 {
+		m.list(4, thriftStruct, 1)
+		m.begin()
+		m.list(1, thriftStruct, len(w.columns))
+		var total int64
+		for i, c := range w.columns {
+			m.begin()
+			m.i64(2, offsets[i])
+			m.structField(3)
+			m.i32(1, c.typ)
+			m.list(2, thriftI32, 1)
+			m.varint(0) // PLAIN
+			m.list(3, thriftBinary, 1)
+			m.varint(uint64(len(c.name)))
+			m.WriteString(c.name)
+			m.i32(4, 0) // UNCOMPRESSED
+			m.i64(5, w.rows)
+			m.i64(6, sizes[i])
+			m.i64(7, sizes[i])
+			m.i64(9, offsets[i])
+			m.end()
+			m.end()
+			total += sizes[i]
+		}
+		m.i64(2, total)
+		m.i64(3, w.rows)
+		m.end()
+	} else {
+		m.list(4, thriftStruct, 0)
+	}
+	m.binary(6, "googleanalytics_exporter")
+	m.end()
+
+	buf.Write(m.Bytes())
+	binary.Write(&buf, binary.LittleEndian, uint32(m.Len()))
+	buf.WriteString("PAR1")
+	return buf.Bytes()
+}
+
+// Thrift compact protocol types used by the Parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol, the
+// encoding of the Parquet metadata. Field IDs are delta encoded against
+// the previous field of the struct being written.
+type thriftWriter struct {
+	bytes.Buffer
+	lastID int16
+	stack  []int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if d := id - t.lastID; d > 0 && d <= 15 {
+		t.WriteByte(byte(d)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.lastID = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+// list starts a list field of n elements, written next as raw values.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.WriteByte(0xf0 | elem)
+		t.varint(uint64(n))
+	}
+}
+
+// structField starts a struct field, ended by end.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// begin starts a struct, the top level one or an element of a list.
+func (t *thriftWriter) begin() {
+	t.stack = append(t.stack, t.lastID)
+	t.lastID = 0
+}
+
+// end stops the current struct.
+func (t *thriftWriter) end() {
+	t.WriteByte(0)
+	t.lastID = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes Thrift compact protocol structs into maps of field
+// ID to value: int64 for integers, string for binaries, []interface{} for
+// lists and map[int16]interface{} for structs.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	c := r.b[r.pos]
+	r.pos++
+	return c
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		panic("invalid varint")
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.pos:]))
+		r.pos += 8
+		return v
+	case thriftBinary:
+		n := int(r.varint())
+		s := string(r.b[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		h := r.byte()
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.varint())
+		}
+		elem := h & 0x0f
+		list := make([]interface{}, n)
+		for i := range list {
+			if elem == 1 || elem == 2 {
+				list[i] = r.byte() == 1
+				continue
+			}
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unknown thrift type %d", typ))
+}
+
+func (r *thriftReader) structure() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return fields
+		}
+		typ := h & 0x0f
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(typ)
+		last = id
+	}
+}
+
+// readParquet decodes the file metadata of a Parquet file and the values of
+// each column, as written by write.
+func readParquet(t *testing.T, data []byte) (map[int16]interface{}, [][]string) {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatal("missing magic number")
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{b: data[len(data)-8-n : len(data)-8]}
+	meta := footer.structure()
+	if footer.pos != n {
+		t.Fatalf("footer of %d bytes, %d decoded", n, footer.pos)
+	}
+
+	schema := meta[2].([]interface{})
+	var columns [][]string
+	for _, rg := range meta[4].([]interface{}) {
+		for i, cc := range rg.(map[int16]interface{})[1].([]interface{}) {
+			cm := cc.(map[int16]interface{})[3].(map[int16]interface{})
+			elem := schema[i+1].(map[int16]interface{})
+			if cm[3].([]interface{})[0] != elem[4] {
+				t.Fatalf("column chunk %d of %v, schema element of %v", i, cm[3], elem[4])
+			}
+			page := &thriftReader{b: data, pos: int(cm[9].(int64))}
+			header := page.structure()
+			size := int(header[3].(int64))
+			rows := int(header[5].(map[int16]interface{})[1].(int64))
+			values := page.b[page.pos : page.pos+size]
+			if got := int64(page.pos+size) - cm[9].(int64); got != cm[6].(int64) {
+				t.Fatalf("column %v: chunk of %d bytes, %d in metadata", elem[4], got, cm[6])
+			}
+
+			var column []string
+			for r := 0; r < rows; r++ {
+				switch {
+				case elem[6] == int64(parquetDate):
+					days := int32(binary.LittleEndian.Uint32(values))
+					column = append(column, time.Unix(int64(days)*86400, 0).UTC().Format("2006-01-02"))
+					values = values[4:]
+				case elem[1] == int64(parquetInt64):
+					column = append(column, strconv.FormatInt(int64(binary.LittleEndian.Uint64(values)), 10))
+					values = values[8:]
+				case elem[1] == int64(parquetDouble):
+					f := math.Float64frombits(binary.LittleEndian.Uint64(values))
+					column = append(column, strconv.FormatFloat(f, 'g', -1, 64))
+					values = values[8:]
+				default:
+					l := binary.LittleEndian.Uint32(values)
+					column = append(column, string(values[4:4+l]))
+					values = values[4+l:]
+				}
+			}
+			if len(values) != 0 {
+				t.Fatalf("column %v: %d bytes left", elem[4], len(values))
+			}
+			columns = append(columns, column)
+		}
+	}
+	return meta, columns
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	w := new(parquetWriter)
+	w.dateColumn("date")
+	w.stringColumn("view")
+	w.int64Column("users")
+	w.doubleColumn("rate")
+	records := [][]string{
+		{"2024-03-01", "ga:1", "42", "0.5"},
+		{"2024-03-02", "ga:2", "-7", "12.25"},
+	}
+	for _, r := range records {
+		if err := w.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	meta, columns := readParquet(t, w.bytes())
+	if rows := meta[3].(int64); rows != 2 {
+		t.Fatalf("got %d rows, want 2", rows)
+	}
+	if n := len(meta[2].([]interface{})); n != 5 {
+		t.Fatalf("got %d schema elements, want 5", n)
+	}
+	for i, column := range columns {
+		for r, got := range column {
+			if got != records[r][i] {
+				t.Errorf("row %d column %d: got %q, want %q", r, i, got, records[r][i])
+			}
+		}
+	}
+}
+
+func TestParquetNoRows(t *testing.T) {
+	w := new(parquetWriter)
+	w.stringColumn("view")
+	w.int64Column("users")
+
+	meta, columns := readParquet(t, w.bytes())
+	if rows := meta[3].(int64); rows != 0 {
+		t.Fatalf("got %d rows, want 0", rows)
+	}
+	if n := len(meta[2].([]interface{})); n != 3 {
+		t.Fatalf("got %d schema elements, want 3", n)
+	}
+	if len(columns) != 0 {
+		t.Fatalf("got %d column chunks, want none", len(columns))
+	}
+}
+
+// TestParquetManyColumns covers lists of 15 elements or more, whose size
+// follows the list header.
+func TestParquetManyColumns(t *testing.T) {
+	w := new(parquetWriter)
+	var record []string
+	for i := 0; i < 20; i++ {
+		w.stringColumn(fmt.Sprintf("c%d", i))
+		record = append(record, fmt.Sprintf("v%d", i))
+	}
+	if err := w.write(record); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, columns := readParquet(t, w.bytes())
+	if n := len(meta[2].([]interface{})); n != 21 {
+		t.Fatalf("got %d schema elements, want 21", n)
+	}
+	if len(columns) != 20 {
+		t.Fatalf("got %d column chunks, want 20", len(columns))
+	}
+	for i, column := range columns {
+		if len(column) != 1 || column[0] != record[i] {
+			t.Errorf("column %d: got %q, want %q", i, column, record[i])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// paused is 1 while collection is paused.
+	paused int32
+
+	pausedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_paused",
+		Help: "Whether collection is paused, the last values are served meanwhile.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(pausedGauge)
+}
+
+// isPaused reports whether collection is paused.
+func isPaused() bool {
+	return atomic.LoadInt32(&paused) == 1
+}
+
+// setPaused pauses or resumes collection.
+func setPaused(p bool) {
+	v := int32(0)
+	if p {
+		v = 1
+	}
+	if atomic.SwapInt32(&paused, v) != v {
+		if p {
+			log.Printf("collection paused")
+		} else {
+			log.Printf("collection resumed")
+		}
+	}
+	pausedGauge.Set(float64(v))
+}
+
+// pauseHandler returns the handler of /-/pause or /-/resume, pausing or
+// resuming collection.
+func pauseHandler(p bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		setPaused(p)
+		if p {
+			fmt.Fprintln(w, "collection paused")
+		} else {
+			fmt.Fprintln(w, "collection resumed")
+		}
+	}
+}
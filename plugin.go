@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// pluginConf defines an exec plugin.
+type pluginConf struct {
+	Name    string   `yaml:"name"`
+	Command []string `yaml:"command"`
+	Timeout int      `yaml:"timeout"`
+}
+
+// pluginRequest is written to the plugin's stdin on every cycle.
+type pluginRequest struct {
+	Plugin   string   `json:"plugin"`
+	Interval int      `json:"interval"`
+	Views    []string `json:"views"`
+}
+
+// pluginResponse is read from the plugin's stdout.
+type pluginResponse struct {
+	Metrics []pluginSample `json:"metrics"`
+}
+
+// pluginSample is a single sample contributed by a plugin.
+type pluginSample struct {
+	Name   string            `json:"name"`
+	Help   string            `json:"help"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// pluginCollector runs an external binary every cycle, exposing the samples
+// it printed until the next successful run. It is registered as an unchecked
+// Prometheus collector, as the metrics are only known at runtime.
+type pluginCollector struct {
+	conf *pluginConf
+
+	mu      sync.Mutex
+	metrics []prometheus.Metric
+}
+
+func newPluginCollector(c *pluginConf) (*pluginCollector, error) {
+	if c.Name == "" || len(c.Command) == 0 {
+		return nil, fmt.Errorf("plugin: name and command are required")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = config.Interval
+	}
+
+	p := &pluginCollector{conf: c}
+	if err := registerAll(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *pluginCollector) name() string { return fmt.Sprintf("plugin %s", p.conf.Name) }
+
+// collect runs the plugin and converts its output into metrics.
+func (p *pluginCollector) collect() error {
+	req := pluginRequest{Plugin: p.conf.Name, Interval: config.Interval}
+	for _, v := range getViews() {
+		req.Views = append(req.Views, v.ViewID)
+	}
+	stdin, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.conf.Timeout)*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.conf.Command[0], p.conf.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("invalid output: %v", err)
+	}
+
+	var metrics []prometheus.Metric
+	for _, s := range resp.Metrics {
+		m, err := s.metric()
+		if err != nil {
+			log.Printf("%s: skipping %s: %v", p.name(), s.Name, err)
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+
+	p.mu.Lock()
+	p.metrics = metrics
+	p.mu.Unlock()
+
+	return nil
+}
+
+// metric converts a sample into a constant Prometheus metric.
+func (s pluginSample) metric() (prometheus.Metric, error) {
+	if !metricName.MatchString(s.Name) {
+		return nil, fmt.Errorf("invalid metric name")
+	}
+
+	valueType := prometheus.GaugeValue
+	switch s.Type {
+	case "", "gauge":
+	case "counter":
+		valueType = prometheus.CounterValue
+	default:
+		return nil, fmt.Errorf("unsupported type %q", s.Type)
+	}
+
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = s.Labels[name]
+	}
+
+	help := s.Help
+	if help == "" {
+		help = fmt.Sprintf("Plugin metric %s", s.Name)
+	}
+
+	return prometheus.NewConstMetric(prometheus.NewDesc(s.Name, help, names, nil), valueType, s.Value, values...)
+}
+
+// Describe implements prometheus.Collector. Nothing is described, which
+// makes the collector unchecked.
+func (p *pluginCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (p *pluginCollector) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, m := range p.metrics {
+		ch <- m
+	}
+}
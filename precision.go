@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// precisionConf rounds the values of a metric, GA percentages and averages
+// come with long decimals that churn exposition diffs and compress poorly.
+type precisionConf struct {
+	// Decimals rounds to a number of decimal places.
+	Decimals *int `yaml:"decimals"`
+	// Significant rounds to a number of significant figures.
+	Significant int `yaml:"significant"`
+}
+
+// check reports inconsistent precisions.
+func (p *precisionConf) check() error {
+	switch {
+	case p.Decimals != nil && p.Significant != 0:
+		return errors.New("decimals and significant are exclusive")
+	case p.Decimals == nil && p.Significant == 0:
+		return errors.New("decimals or significant is required")
+	case p.Decimals != nil && *p.Decimals < 0:
+		return errors.New("decimals is negative")
+	case p.Significant < 0:
+		return errors.New("significant is negative")
+	}
+	return nil
+}
+
+// round rounds a value to the precision. A nil precisionConf keeps it.
+func (p *precisionConf) round(valf float64) float64 {
+	if p == nil || valf == 0 || math.IsNaN(valf) || math.IsInf(valf, 0) {
+		return valf
+	}
+	decimals := 0
+	if p.Decimals != nil {
+		decimals = *p.Decimals
+	} else {
+		decimals = p.Significant - int(math.Floor(math.Log10(math.Abs(valf)))) - 1
+	}
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(valf*scale) / scale
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/googleapi"
+)
+
+// newDisabledVec returns the info series of the metrics of a view
+// quarantined because the API rejects their columns.
+func newDisabledVec(v *view) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_exporter_disabled_metric",
+		Help:        "Metric no longer queried because the API rejected its columns, until the config is reloaded.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric", "dimensions", "reason"})
+}
+
+// disable quarantines a metric of the view: it is no longer scheduled and
+// its series are dropped, the other metrics are still collected. Reloading
+// the config lifts the quarantine.
+func (v *view) disable(metric, reason string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.disabled[metric] {
+		return
+	}
+	v.disabled[metric] = true
+	v.disabledVec.WithLabelValues(metric, v.getDimensions(metric), reason).Set(1)
+	v.nextRunTime.DeleteLabelValues(metric)
+	for _, name := range strings.Split(metric, ",") {
+		if g, ok := v.promGauge[name]; ok {
+			v.registry.Unregister(g)
+			delete(v.promGauge, name)
+			delete(v.gaugeSet, name)
+		}
+		if vec, ok := v.promGaugeVec[name]; ok {
+			v.registry.Unregister(vec)
+			delete(v.promGaugeVec, name)
+			delete(v.vecUsed, name)
+			v.limit.release(len(v.series[name]))
+			delete(v.series, name)
+			v.seriesCount.DeleteLabelValues(name)
+		}
+		v.dropChange(name)
+	}
+	log.Printf("view %s: %s disabled: %s", v.displayName(), metric, reason)
+}
+
+// rejection returns the reason the API rejected the columns of a query,
+// "" when err is not such a rejection. Only 400s naming a column of the
+// query are, others, e.g. of the hostname filter, are ordinary errors.
+func rejection(err error, q *realtimeQuery) string {
+	if cerr, ok := err.(*categorizedError); ok {
+		if cerr.category != errInvalid {
+			return ""
+		}
+		err = cerr.err
+	}
+	gerr, ok := err.(*googleapi.Error)
+	if !ok || gerr.Code != 400 {
+		return ""
+	}
+	messages := []string{gerr.Message}
+	for _, e := range gerr.Errors {
+		messages = append(messages, e.Message)
+	}
+	for _, column := range strings.Split(q.metric+","+q.dimensions, ",") {
+		if column == "" {
+			continue
+		}
+		for _, m := range messages {
+			if strings.Contains(m, column) {
+				return gerr.Message
+			}
+		}
+	}
+	return ""
+}
+
+// quarantined reports whether metrics of the view are disabled. A reload
+// replaces such a view even when its config is unchanged, to query them
+// again.
+func (v *view) quarantined() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.disabled) > 0
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var configChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "ga_exporter_config_changes_total",
+	Help: "Views, metrics and dimensions added, removed or changed by config reloads.",
+}, []string{"kind", "change"})
+
+// reloadMu serializes reloads, from SIGHUP and the admin listener.
+var reloadMu sync.Mutex
+
+func init() {
+	prometheus.MustRegister(configChanges)
+}
+
+// configChange is a difference between two configs.
+type configChange struct {
+	kind   string // view, metric or dimensions
+	change string // added, removed or changed
+	name   string
+}
+
+func (c configChange) String() string {
+	return fmt.Sprintf("%s %s: %s", c.kind, c.change, c.name)
+}
+
+// reloadOnSIGHUP reloads the config file on SIGHUP, it never returns.
+func reloadOnSIGHUP() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	for range ch {
+		if err := reload(); err != nil {
+			log.Printf("config reload: %v", err)
+		}
+	}
+}
+
+// reload applies the views of the config file, logging what changed. Views
+// whose config is unchanged keep their state, unless metrics of theirs are
+// disabled. Other settings require a restart.
+func reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	nc, err := readConf(conffile)
+	if err != nil {
+		configLastReloadSuccessful.Set(0)
+		return err
+	}
+	for _, vc := range nc.Views {
+		if _, ok := tenants[vc.Tenant]; vc.Tenant != "" && !ok {
+			configLastReloadSuccessful.Set(0)
+			return fmt.Errorf("view %s: unknown tenant %q", vc.displayName(), vc.Tenant)
+		}
+	}
+	nc.setDefaults()
+
+	changes := diffViews(config.Views, nc.Views)
+	for _, c := range changes {
+		log.Printf("config reload: %s", c)
+		configChanges.WithLabelValues(c.kind, c.change).Inc()
+	}
+	if !sameSettings(config, nc) {
+		log.Printf("config reload: settings other than views changed, they apply after a restart")
+	}
+
+	running := make(map[string]*view)
+	for _, v := range getViews() {
+		running[v.ViewID] = v
+	}
+	kept := make(map[string]*view)
+	for _, vc := range nc.Views {
+		if v, ok := running[vc.ViewID]; ok && reflect.DeepEqual(v.viewConf, vc) && !v.quarantined() {
+			kept[vc.ViewID] = v
+			delete(running, vc.ViewID)
+		}
+	}
+	// The views replaced or dropped give their series back to the limit
+	// before the new ones take theirs.
+	for _, v := range running {
+		v.retire()
+	}
+	var vs []*view
+	for _, vc := range nc.Views {
+		if v, ok := kept[vc.ViewID]; ok {
+			vs = append(vs, v)
+			continue
+		}
+		vs = append(vs, newView(vc))
+	}
+
+	viewsMu.Lock()
+	views = vs
+	config.Views = nc.Views
+	viewsMu.Unlock()
+
+	configLoaded(nc.hash)
+	log.Printf("config reloaded, %d changes", len(changes))
+	return nil
+}
+
+// diffViews returns the views, metrics and dimensions added, removed or
+// changed from one config to the other.
+func diffViews(from, to []*viewConf) []configChange {
+	var changes []configChange
+	old := make(map[string]*viewConf)
+	for _, vc := range from {
+		old[vc.ViewID] = vc
+	}
+	seen := make(map[string]bool)
+
+	for _, vc := range to {
+		seen[vc.ViewID] = true
+		ovc, ok := old[vc.ViewID]
+		if !ok {
+			changes = append(changes, configChange{"view", "added", vc.ViewID})
+			continue
+		}
+		if reflect.DeepEqual(ovc, vc) {
+			continue
+		}
+		changes = append(changes, configChange{"view", "changed", vc.ViewID})
+
+		metrics := make(map[string]bool)
+		for _, m := range ovc.queries() {
+			metrics[m] = true
+		}
+		for _, m := range vc.queries() {
+			name := vc.ViewID + " " + m
+			if !metrics[m] {
+				changes = append(changes, configChange{"metric", "added", name})
+				continue
+			}
+			delete(metrics, m)
+
+			od, nd := ovc.getDimensions(m), vc.getDimensions(m)
+			switch {
+			case od == nd:
+			case od == "":
+				changes = append(changes, configChange{"dimensions", "added", name + " " + nd})
+			case nd == "":
+				changes = append(changes, configChange{"dimensions", "removed", name + " " + od})
+			default:
+				changes = append(changes, configChange{"dimensions", "changed", fmt.Sprintf("%s %s -> %s", name, od, nd)})
+			}
+		}
+		for _, m := range ovc.queries() {
+			if metrics[m] {
+				changes = append(changes, configChange{"metric", "removed", vc.ViewID + " " + m})
+			}
+		}
+	}
+
+	for _, vc := range from {
+		if !seen[vc.ViewID] {
+			changes = append(changes, configChange{"view", "removed", vc.ViewID})
+		}
+	}
+
+	return changes
+}
+
+// sameSettings reports whether two configs only differ by their views.
+func sameSettings(a, b *conf) bool {
+	ca, cb := *a, *b
+	for _, c := range []*conf{&ca, &cb} {
+		c.Views = nil
+		c.ViewID, c.Metrics, c.Dimensions = "", nil, nil
+		c.hash = ""
+	}
+	return reflect.DeepEqual(ca, cb)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analyticsreporting/v4"
+)
+
+// reportConf defines a Reporting API query.
+type reportConf struct {
+	Name       string   `yaml:"name"`
+	ViewID     string   `yaml:"viewid"`
+	StartDate  string   `yaml:"start_date"`
+	EndDate    string   `yaml:"end_date"`
+	Metrics    []string `yaml:"metrics"`
+	Dimensions []string `yaml:"dimensions"`
+	// Interval in seconds between queries, reports change slowly and cost
+	// more quota than RealTime queries.
+	Interval int `yaml:"interval"`
+	// UTCDates computes the relative dates, today, yesterday and
+	// NdaysAgo, in UTC instead of the time zone of the view.
+	UTCDates bool `yaml:"utc_dates"`
+}
+
+// reportCollector exports Reporting API (v4) reports. Every metric is a
+// GaugeVec labeled by report, viewid and the report dimensions.
+type reportCollector struct {
+	conf   []*reportConf
+	svc    *analyticsreporting.Service
+	zones  *viewTimezones
+	golden *prometheus.GaugeVec
+
+	// running serializes collections, series and lastRun are only used
+	// while held.
+	running sync.Mutex
+	series  map[string][][]string
+	lastRun map[string]time.Time
+
+	mu     sync.Mutex
+	gauges map[string]*prometheus.GaugeVec
+}
+
+func newReportCollector(httpClient *http.Client, rcs []*reportConf, zones *viewTimezones) (*reportCollector, error) {
+	svc, err := analyticsreporting.New(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, rc := range rcs {
+		if rc.Name == "" || names[rc.Name] {
+			return nil, fmt.Errorf("reports: missing or duplicate name %q", rc.Name)
+		}
+		names[rc.Name] = true
+		if rc.StartDate == "" {
+			rc.StartDate = "today"
+		}
+		if rc.EndDate == "" {
+			rc.EndDate = "today"
+		}
+		if rc.Interval <= 0 {
+			rc.Interval = 3600
+		}
+	}
+
+	r := &reportCollector{
+		conf:  rcs,
+		svc:   svc,
+		zones: zones,
+		golden: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ga_report_data_golden",
+			Help: "Whether the report data is golden, i.e. will not change anymore when queried again.",
+		}, []string{"report", "viewid"}),
+		gauges:  make(map[string]*prometheus.GaugeVec),
+		series:  make(map[string][][]string),
+		lastRun: make(map[string]time.Time),
+	}
+	if err := registerAll(r.golden); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *reportCollector) name() string { return "reports" }
+
+// collect runs the reports whose interval elapsed.
+func (r *reportCollector) collect() error {
+	r.running.Lock()
+	defer r.running.Unlock()
+
+	for _, rc := range r.conf {
+		if time.Since(r.lastRun[rc.Name]) < time.Duration(rc.Interval)*time.Second {
+			continue
+		}
+		r.lastRun[rc.Name] = time.Now()
+
+		if err := r.report(rc); err != nil {
+			return fmt.Errorf("report %s: %v", rc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// report queries a single report and sets its gauges.
+func (r *reportCollector) report(rc *reportConf) error {
+	report, err := r.query(rc, rc.StartDate, rc.EndDate)
+	if err != nil || report == nil {
+		return err
+	}
+
+	golden := 0.0
+	if report.Data.IsDataGolden {
+		golden = 1
+	}
+	r.golden.WithLabelValues(rc.Name, rc.ViewID).Set(golden)
+
+	labels := []string{"report", "viewid"}
+	for _, d := range report.ColumnHeader.Dimensions {
+		labels = append(labels, dimensionLabel(d))
+	}
+
+	for i, h := range report.ColumnHeader.MetricHeader.MetricHeaderEntries {
+		vec, err := r.gaugeVec(h.Name, labels)
+		if err != nil {
+			return err
+		}
+
+		// Reports can share a metric, only this report's stale rows are
+		// deleted.
+		key := rc.Name + "/" + h.Name
+		for _, values := range r.series[key] {
+			vec.DeleteLabelValues(values...)
+		}
+		r.series[key] = nil
+
+		for _, row := range report.Data.Rows {
+			if len(row.Metrics) == 0 || i >= len(row.Metrics[0].Values) {
+				continue
+			}
+			valf, _ := strconv.ParseFloat(row.Metrics[0].Values[i], 64)
+			values := append([]string{rc.Name, rc.ViewID}, row.Dimensions...)
+			g, err := vec.GetMetricWithLabelValues(values...)
+			if err != nil {
+				return fmt.Errorf("%s: %v", h.Name, err)
+			}
+			g.Set(valf)
+			r.series[key] = append(r.series[key], values)
+		}
+	}
+
+	return nil
+}
+
+// now returns the current time in the time zone the dates of a report are
+// computed in: UTC with utc_dates, else the time zone of the view.
+func (r *reportCollector) now(rc *reportConf) (time.Time, error) {
+	if rc.UTCDates {
+		return time.Now().UTC(), nil
+	}
+	loc, err := r.zones.location(rc.ViewID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
+
+// query runs a report over a date range, nil when it has no data. With
+// utc_dates, relative dates are resolved in UTC.
+func (r *reportCollector) query(rc *reportConf, startDate, endDate string) (*analyticsreporting.Report, error) {
+	if rc.UTCDates {
+		now := time.Now().UTC()
+		startDate, endDate = resolveDate(startDate, now), resolveDate(endDate, now)
+	}
+	req := &analyticsreporting.ReportRequest{
+		ViewId:     strings.TrimPrefix(rc.ViewID, "ga:"),
+		DateRanges: []*analyticsreporting.DateRange{{StartDate: startDate, EndDate: endDate}},
+		PageSize:   10000,
+	}
+	for _, m := range rc.Metrics {
+		req.Metrics = append(req.Metrics, &analyticsreporting.Metric{Expression: m})
+	}
+	for _, d := range rc.Dimensions {
+		req.Dimensions = append(req.Dimensions, &analyticsreporting.Dimension{Name: d})
+	}
+
+	resp, err := r.svc.Reports.BatchGet(&analyticsreporting.GetReportsRequest{
+		ReportRequests: []*analyticsreporting.ReportRequest{req},
+	}).Do()
+	if err != nil {
+		return nil, categorize(rc.ViewID, "", err)
+	}
+	if len(resp.Reports) == 0 || resp.Reports[0].Data == nil {
+		return nil, nil
+	}
+	return resp.Reports[0], nil
+}
+
+// gaugeVec returns the GaugeVec of a report metric, named by the metric
+// naming template. The reports sharing a metric have the same dimensions,
+// as checked with the config.
+func (r *reportCollector) gaugeVec(metric string, labels []string) (*prometheus.GaugeVec, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if vec, ok := r.gauges[metric]; ok {
+		return vec, nil
+	}
+
+	name, err := renderName(nameTemplate, &nameData{View: &viewConf{}, Metric: metric})
+	if err != nil {
+		return nil, err
+	}
+	vec := newGaugeVec(name, fmt.Sprintf("Google Analytics %s", metric), labels)
+	if err := prometheus.Register(vec); err != nil {
+		return nil, fmt.Errorf("%s: %v", metric, err)
+	}
+	r.gauges[metric] = vec
+
+	return vec, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/googleapi"
+)
+
+// Defaults of the retry policy, failed queries are not retried.
+const (
+	defaultRetryAttempts    = 1
+	defaultRetryBaseBackoff = 1
+	defaultRetryMaxBackoff  = 30
+)
+
+// defaultRetryableCodes are the HTTP status codes of transient failures.
+var defaultRetryableCodes = []int{429, 500, 502, 503, 504}
+
+var queryRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "ga_exporter_query_retries_total",
+	Help: "Failed queries of a view retried.",
+}, []string{"viewid", "alias"})
+
+func init() {
+	prometheus.MustRegister(queryRetries)
+}
+
+// retryConf is the retry policy of the queries of a view.
+type retryConf struct {
+	// MaxAttempts of a query, including the first. Defaults to 1.
+	MaxAttempts int `yaml:"max_attempts"`
+	// BaseBackoff and MaxBackoff in seconds bound the exponential backoff
+	// between attempts, which is jittered. Default to 1 and 30.
+	BaseBackoff float64 `yaml:"base_backoff"`
+	MaxBackoff  float64 `yaml:"max_backoff"`
+	// RetryableCodes are the HTTP status codes retried, network errors are
+	// retried too. Default to 429, 500, 502, 503 and 504.
+	RetryableCodes []int `yaml:"retryable_codes"`
+}
+
+// setDefaults sets the defaults of unset retry settings.
+func (rc *retryConf) setDefaults() {
+	if rc.MaxAttempts <= 0 {
+		rc.MaxAttempts = defaultRetryAttempts
+	}
+	if rc.BaseBackoff <= 0 {
+		rc.BaseBackoff = defaultRetryBaseBackoff
+	}
+	if rc.MaxBackoff <= 0 {
+		rc.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if len(rc.RetryableCodes) == 0 {
+		rc.RetryableCodes = defaultRetryableCodes
+	}
+}
+
+// retryable reports whether a failed attempt is retried.
+func (rc *retryConf) retryable(err error) bool {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	switch e := err.(type) {
+	case *googleapi.Error:
+		for _, code := range rc.RetryableCodes {
+			if e.Code == code {
+				return true
+			}
+		}
+	case net.Error:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before the attempt following attempt, counted
+// from 1, with full jitter.
+func (rc *retryConf) backoff(attempt int) time.Duration {
+	d := math.Min(rc.MaxBackoff, rc.BaseBackoff*math.Pow(2, float64(attempt-1)))
+	return time.Duration(rand.Float64() * d * float64(time.Second))
+}
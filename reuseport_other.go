@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package main
+
+import "syscall"
+
+// setReusePort is a no-op where SO_REUSEPORT is not supported, a handoff
+// then leaves the port unbound until the previous process exits.
+func setReusePort(network, address string, c syscall.RawConn) error {
+	return nil
+}
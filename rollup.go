@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// activeUsersRollup exposes the active users summed across the views, from
+// the last value of every view: a sum() in PromQL drops the views whose
+// series went stale and dips. Views of tenants are left out, their metrics
+// are only served to the tenant.
+type activeUsersRollup struct {
+	desc *prometheus.Desc
+}
+
+func init() {
+	prometheus.MustRegister(&activeUsersRollup{
+		desc: prometheus.NewDesc("ga_active_users_total",
+			"Active users summed across views, from the last value of every view.",
+			nil, prometheus.Labels{"scope": "all_views"}),
+	})
+}
+
+// Describe implements prometheus.Collector.
+func (r *activeUsersRollup) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.desc
+}
+
+// Collect implements prometheus.Collector.
+func (r *activeUsersRollup) Collect(ch chan<- prometheus.Metric) {
+	total := 0.0
+	for _, v := range getViews() {
+		if v.Tenant == "" {
+			total += v.activeUsers()
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(r.desc, prometheus.GaugeValue, total)
+}
+
+// activeUsers returns the last rt:activeUsers value of a view, its total
+// when it is dimensioned, 0 when not collected.
+func (v *view) activeUsers() float64 {
+	v.mu.Lock()
+	g, ok := v.promGauge["rt:activeUsers"]
+	if !ok {
+		g, ok = v.promTotal["rt:activeUsers"]
+	}
+	v.mu.Unlock()
+	if !ok {
+		return 0
+	}
+
+	var m dto.Metric
+	if err := g.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetGauge().GetValue()
+}
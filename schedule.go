@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// schedulerLag is how late metric queries are due compared to when they
+// were scheduled, it grows when cycles overrun the intervals.
+var schedulerLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "ga_exporter_scheduler_lag_seconds",
+	Help:    "Delay between the time a metric query was scheduled for and the time it was found due.",
+	Buckets: []float64{1, 2, 5, 10, 30, 60, 120, 300},
+})
+
+func init() {
+	prometheus.MustRegister(schedulerLag)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleRule sets the polling interval of a metric on the given days and
+// hours. Rules are evaluated in order, the first matching rule applies.
+type scheduleRule struct {
+	// Days are weekday abbreviations, e.g. [mon, tue], every day if empty.
+	Days []string `yaml:"days"`
+	// Hours is a range of hours of the day, 0 to 23, e.g. "9-18" from
+	// 9:00 to 17:59 or "18-0" to midnight, the whole day if empty.
+	Hours string `yaml:"hours"`
+	// Interval in seconds between queries.
+	Interval int `yaml:"interval"`
+}
+
+// check validates the rule.
+func (r *scheduleRule) check() error {
+	if r.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	for _, d := range r.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown day %q", d)
+		}
+	}
+	if r.Hours != "" {
+		if _, _, err := r.hourRange(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hourRange parses Hours into a [from, to) range.
+func (r *scheduleRule) hourRange() (int, int, error) {
+	parts := strings.Split(r.Hours, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("hours %q: expected a range like 9-18", r.Hours)
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours %q: %v", r.Hours, err)
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours %q: %v", r.Hours, err)
+	}
+	// Ranges to midnight end at 0, e.g. 18-0.
+	if from < 0 || from > 23 || to < 0 || to > 23 || from == to {
+		return 0, 0, fmt.Errorf("hours %q: out of range, hours are 0 to 23", r.Hours)
+	}
+
+	return from, to, nil
+}
+
+// matches reports whether the rule applies at t. Hour ranges may wrap
+// around midnight, e.g. "22-6".
+func (r *scheduleRule) matches(t time.Time) bool {
+	if len(r.Days) > 0 {
+		day := false
+		for _, d := range r.Days {
+			day = day || weekdays[strings.ToLower(d)] == t.Weekday()
+		}
+		if !day {
+			return false
+		}
+	}
+
+	if r.Hours != "" {
+		from, to, _ := r.hourRange()
+		h := t.Hour()
+		if from < to {
+			return h >= from && h < to
+		}
+		return h >= from || h < to
+	}
+
+	return true
+}
+
+// interval returns the polling interval of a metric at t, the global
+// interval when no schedule rule matches. Breakdowns follow the schedule of
+// their metric unless they have their own.
+func (v *view) interval(metric string, t time.Time) time.Duration {
+	rules, ok := v.Schedules[metric]
+	if !ok {
+		rules = v.Schedules[v.baseMetric(metric)]
+	}
+	for _, r := range rules {
+		if r.matches(t) {
+			return time.Duration(r.Interval) * time.Second
+		}
+	}
+
+	return time.Duration(config.Interval) * time.Second
+}
+
+// newSchedulerVecs returns the vectors of the next run and of the runs
+// skipped of the metrics of a view.
+func newSchedulerVecs(v *view) (*prometheus.GaugeVec, *prometheus.CounterVec) {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "ga_exporter_scheduler_next_run_timestamp_seconds",
+			Help:        "Time the next query of a metric is scheduled for.",
+			ConstLabels: v.selfLabels(),
+		}, []string{"metric"}),
+		prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "ga_exporter_scheduler_skipped_runs_total",
+			Help:        "Scheduled queries of a metric skipped because previous cycles overran its interval.",
+			ConstLabels: v.selfLabels(),
+		}, []string{"metric"})
+}
+
+// reschedule makes a metric due at the next check, e.g. when its query was
+// cancelled.
+func (v *view) reschedule(metric string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.nextRun, metric)
+}
+
+// dueMetrics returns the metrics to query at t, scheduling their next run.
+// Runs a metric missed while a cycle overran are skipped, not caught up,
+// disabled metrics are never due.
+func (v *view) dueMetrics(t time.Time) []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var due []string
+	for _, metric := range v.queries() {
+		next := v.nextRun[metric]
+		if t.Before(next) || v.disabled[metric] {
+			continue
+		}
+		due = append(due, metric)
+		interval := v.interval(metric, t)
+		if !next.IsZero() {
+			// With a scrape trigger, queries run up to the debounce early.
+			lag := t.Sub(next)
+			if lag < 0 {
+				lag = 0
+			}
+			schedulerLag.Observe(lag.Seconds())
+			if skipped := int(lag / interval); skipped > 0 {
+				v.skippedRuns.WithLabelValues(metric).Add(float64(skipped))
+			}
+		}
+		v.nextRun[metric] = t.Add(interval)
+		v.nextRunTime.WithLabelValues(metric).Set(float64(v.nextRun[metric].Unix()))
+	}
+
+	return due
+}
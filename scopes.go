@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/analytics/v3"
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/searchconsole/v1"
+	"google.golang.org/api/storage/v1"
+	"google.golang.org/api/youtubeanalytics/v2"
+)
+
+// getScopes returns the OAuth scopes requested for the access token. Unless
+// overridden with `scopes`, they are derived from the enabled collectors.
+// With `enforce_readonly` any scope granting write access is refused.
+func (c *conf) getScopes() ([]string, error) {
+	scopes := c.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{analytics.AnalyticsReadonlyScope}
+		if c.SearchConsole != nil {
+			scopes = append(scopes, searchconsole.WebmastersReadonlyScope)
+		}
+		if c.GoogleAds != nil {
+			scopes = append(scopes, adsScope)
+		}
+		if c.YouTube != nil {
+			scopes = append(scopes, youtubeanalytics.YtAnalyticsReadonlyScope)
+		}
+		if c.BigQuery != nil {
+			scopes = append(scopes, bigquery.BigqueryInsertdataScope)
+		}
+		if c.Snapshots != nil && strings.HasPrefix(c.Snapshots.Destination, "gs://") ||
+			c.ExpositionArchive != nil && strings.HasPrefix(c.ExpositionArchive.Destination, "gs://") {
+			scopes = append(scopes, storage.DevstorageReadWriteScope)
+		}
+	}
+
+	if c.EnforceReadonly {
+		for _, scope := range scopes {
+			if !strings.HasSuffix(scope, ".readonly") {
+				return nil, fmt.Errorf("scope %s is not read-only", scope)
+			}
+		}
+	}
+
+	return scopes, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultScrapeDebounce is 5 seconds.
+const defaultScrapeDebounce = 5
+
+// scrapeTriggerConf aligns collection on the scrapes of the metrics instead
+// of an independent timer.
+type scrapeTriggerConf struct {
+	// Debounce in seconds, scrapes closer to the last one that triggered
+	// collection, e.g. of a second Prometheus, don't trigger it. Queries
+	// due within as long run early. Defaults to 5.
+	Debounce int `yaml:"debounce"`
+}
+
+// scrapeTrigger wakes the collection loop after every scrape, so each
+// scrape is followed by one cycle and the values served don't drift in
+// phase with the Prometheus scrape interval.
+type scrapeTrigger struct {
+	debounce time.Duration
+	ch       chan struct{}
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newScrapeTrigger(sc *scrapeTriggerConf) *scrapeTrigger {
+	return &scrapeTrigger{
+		debounce: time.Duration(sc.Debounce) * time.Second,
+		ch:       make(chan struct{}, 1),
+	}
+}
+
+// handler wraps a metrics handler, triggering collection once the scrape
+// is served.
+func (t *scrapeTrigger) handler(h http.Handler) http.Handler {
+	if t == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		t.fire()
+	})
+}
+
+// fire triggers collection, unless it was triggered within the debounce
+// window.
+func (t *scrapeTrigger) fire() {
+	t.mu.Lock()
+	now := time.Now()
+	if now.Sub(t.last) < t.debounce {
+		t.mu.Unlock()
+		return
+	}
+	t.last = now
+	t.mu.Unlock()
+
+	select {
+	case t.ch <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until the next scrape. A nil scrapeTrigger waits a second,
+// the timer of the collection loop.
+func (t *scrapeTrigger) wait() {
+	if t == nil {
+		time.Sleep(time.Second)
+		return
+	}
+	<-t.ch
+}
+
+// lead returns how early due queries and collectors run, scrapes don't
+// land exactly an interval apart.
+func (t *scrapeTrigger) lead() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.debounce
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/searchconsole/v1"
+)
+
+// gscConf defines the Search Console collector parameters.
+type gscConf struct {
+	Sites     []string `yaml:"sites"`
+	Dimension string   `yaml:"dimension"`
+	Top       int64    `yaml:"top"`
+	Days      int      `yaml:"days"`
+}
+
+// gscCollector exports Search Console clicks, impressions and average
+// position for the top queries or pages of every configured site.
+type gscCollector struct {
+	conf        *gscConf
+	svc         *searchconsole.Service
+	clicks      *prometheus.GaugeVec
+	impressions *prometheus.GaugeVec
+	position    *prometheus.GaugeVec
+}
+
+func newGSCCollector(httpClient *http.Client, c *gscConf) (*gscCollector, error) {
+	if c.Dimension == "" {
+		c.Dimension = "query"
+	}
+	if c.Dimension != "query" && c.Dimension != "page" {
+		return nil, fmt.Errorf("searchconsole: unsupported dimension %q, expected query or page", c.Dimension)
+	}
+	if c.Top <= 0 {
+		c.Top = 10
+	}
+	if c.Days <= 0 {
+		c.Days = 3
+	}
+
+	svc, err := searchconsole.New(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []string{"site", c.Dimension}
+	g := &gscCollector{
+		conf:        c,
+		svc:         svc,
+		clicks:      newGaugeVec("gsc_clicks", "Search Console clicks", labels),
+		impressions: newGaugeVec("gsc_impressions", "Search Console impressions", labels),
+		position:    newGaugeVec("gsc_position", "Search Console average position", labels),
+	}
+
+	if err := registerAll(g.clicks, g.impressions, g.position); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *gscCollector) name() string { return "searchconsole" }
+
+// collect queries the Search Analytics API for every site. Values cover
+// the last conf.Days days, as Search Console data lags by a couple of days.
+func (g *gscCollector) collect() error {
+	end := time.Now()
+	start := end.AddDate(0, 0, -g.conf.Days)
+
+	rows := make(map[string][]*searchconsole.ApiDataRow)
+	for _, site := range g.conf.Sites {
+		req := &searchconsole.SearchAnalyticsQueryRequest{
+			StartDate:  start.Format("2006-01-02"),
+			EndDate:    end.Format("2006-01-02"),
+			Dimensions: []string{g.conf.Dimension},
+			RowLimit:   g.conf.Top,
+		}
+		resp, err := g.svc.Searchanalytics.Query(site, req).Do()
+		if err != nil {
+			return fmt.Errorf("site %s: %v", site, err)
+		}
+		rows[site] = resp.Rows
+	}
+
+	// Top-N membership changes between cycles, drop stale series first.
+	g.clicks.Reset()
+	g.impressions.Reset()
+	g.position.Reset()
+	for site, siteRows := range rows {
+		for _, row := range siteRows {
+			if len(row.Keys) == 0 {
+				continue
+			}
+			g.clicks.WithLabelValues(site, row.Keys[0]).Set(row.Clicks)
+			g.impressions.WithLabelValues(site, row.Keys[0]).Set(row.Impressions)
+			g.position.WithLabelValues(site, row.Keys[0]).Set(row.Position)
+		}
+	}
+
+	return nil
+}
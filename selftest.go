@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/api/analytics/v3"
+)
+
+// selftest runs one collection of every configured metric, scrapes the
+// exporter and lints the result. Responses are read from a fixtures file,
+// a JSON object of RealTime API responses by metric, or from the API when
+// no fixtures are given. It returns the exit status.
+func selftest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	fixtures := fs.String("fixtures", "", "JSON file of RealTime API responses by metric, the API is queried when empty.")
+	fs.Parse(args)
+
+	httpClient := http.DefaultClient
+	if *fixtures == "" {
+		httpClient = newHTTPClient()
+	}
+	as, err := analytics.New(httpClient)
+	if err != nil {
+		fmt.Printf("selftest: %v\n", err)
+		return 1
+	}
+	if *fixtures != "" {
+		srv, err := newFixturesServer(*fixtures)
+		if err != nil {
+			fmt.Printf("selftest: %v\n", err)
+			return 1
+		}
+		defer srv.Close()
+		as.BasePath = srv.URL + "/"
+	}
+	rts := analytics.NewDataRealtimeService(as)
+
+	var problems []string
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer}
+	for _, v := range views {
+		gatherers = append(gatherers, v.registry)
+		for _, metric := range v.queries() {
+			if err := v.collectMetric(rts, metric, v.getDimensions(metric)); err != nil {
+				problems = append(problems, fmt.Sprintf("view %s: %s: %v", v.displayName(), metric, err))
+			}
+		}
+	}
+
+	families, err := scrape(gatherers)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("scrape: %v", err))
+	}
+	problems = append(problems, lintMetrics(families)...)
+
+	for _, p := range problems {
+		fmt.Printf("selftest: %s\n", p)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	fmt.Printf("selftest OK, %d metric families\n", len(families))
+	return 0
+}
+
+// newFixturesServer serves the responses of a fixtures file in place of the
+// RealTime API, matched by the metrics parameter of the request.
+func newFixturesServer(filename string) (*httptest.Server, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := fixtures[r.URL.Query().Get("metrics")]
+		if !ok {
+			http.Error(w, `{"error": {"code": 404, "message": "no fixture"}}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	})), nil
+}
+
+// scrape serves the gatherers over HTTP and parses the exposition, as
+// Prometheus would see it.
+func scrape(g prometheus.Gatherer) (map[string]*dto.MetricFamily, error) {
+	srv := httptest.NewServer(promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var p expfmt.TextParser
+	return p.TextToMetricFamilies(resp.Body)
+}
+
+// lintMetrics reports the problems promtool check metrics would, that apply
+// to gauges and counters.
+func lintMetrics(families map[string]*dto.MetricFamily) []string {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		mf := families[name]
+		if mf.GetHelp() == "" {
+			problems = append(problems, fmt.Sprintf("%s: no help text", name))
+		}
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			if !strings.HasSuffix(name, "_total") {
+				problems = append(problems, fmt.Sprintf("%s: counter metrics should have \"_total\" suffix", name))
+			}
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			if strings.HasSuffix(name, "_total") {
+				problems = append(problems, fmt.Sprintf("%s: non-counter metrics should not have \"_total\" suffix", name))
+			}
+			for _, suffix := range []string{"_count", "_sum", "_bucket"} {
+				if strings.HasSuffix(name, suffix) {
+					problems = append(problems, fmt.Sprintf("%s: non-histogram and non-summary metrics should not have %q suffix", name, suffix))
+				}
+			}
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if strings.HasPrefix(l.GetName(), "__") {
+					problems = append(problems, fmt.Sprintf("%s: label %s is reserved", name, l.GetName()))
+				}
+			}
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// globalSeriesLimit is shared by all views, set from series_limit.
+	globalSeriesLimit *seriesLimit
+
+	seriesLimitHit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_series_limit_hit",
+		Help: "Whether the series limit is reached and new label combinations are dropped.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(seriesLimitHit)
+}
+
+// seriesLimit caps the label combinations exported across all views, so a
+// label explosion is contained rather than exhausting memory.
+type seriesLimit struct {
+	max int
+
+	mu  sync.Mutex
+	n   int
+	hit bool
+}
+
+// add accounts for a new series, it reports false when the limit is
+// reached. A nil seriesLimit admits every series.
+func (l *seriesLimit) add(metric string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.n >= l.max {
+		if !l.hit {
+			log.Printf("SERIES LIMIT of %d REACHED, new label combinations of %s and other metrics are dropped", l.max, metric)
+			seriesLimitHit.Set(1)
+		}
+		l.hit = true
+		return false
+	}
+	l.n++
+	return true
+}
+
+// release accounts for n series removed.
+func (l *seriesLimit) release(n int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.n -= n
+	if l.hit && l.n < l.max {
+		l.hit = false
+		seriesLimitHit.Set(0)
+	}
+}
+
+// admitSeries reports whether a label combination of a metric may be
+// exported, registering it when new.
+func (v *view) admitSeries(metric string, labelValues []string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	if v.series[metric][key] {
+		return true
+	}
+	if !v.limit.add(metric) {
+		return false
+	}
+	if v.series[metric] == nil {
+		v.series[metric] = make(map[string]bool)
+	}
+	v.series[metric][key] = true
+	v.seriesCount.WithLabelValues(metric).Set(float64(len(v.series[metric])))
+
+	return true
+}
+
+// retire releases the series of a view replaced or dropped by a reload.
+// Queries still in flight for it no longer count against the limit.
+func (v *view) retire() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	n := 0
+	for _, keys := range v.series {
+		n += len(keys)
+	}
+	v.limit.release(n)
+	v.limit = nil
+	v.series = make(map[string]map[string]bool)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultSnapshotDays is the number of past days checked for a missing
+// snapshot.
+const defaultSnapshotDays = 3
+
+// snapshotConf defines the daily snapshots of the reports.
+type snapshotConf struct {
+	// Destination is a gs://bucket/prefix or s3://bucket/prefix URL, or a
+	// local directory.
+	Destination string `yaml:"destination"`
+	// Format of the files, csv or parquet. Defaults to csv.
+	Format string `yaml:"format"`
+	// Reports snapshotted, by name, all of them when empty.
+	Reports []string `yaml:"reports"`
+	// Days in the past a snapshot is still written for, once its data is
+	// golden. Defaults to 3.
+	Days int `yaml:"days"`
+}
+
+var snapshotsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "ga_exporter_snapshots_written_total",
+	Help: "Daily report snapshots written.",
+}, []string{"report"})
+
+func init() {
+	prometheus.MustRegister(snapshotsWritten)
+}
+
+// snapshotCollector writes the final values of every day of the reports to
+// a file per report and day, <report>/<YYYY-MM-DD>.<format>, for long-term
+// analysis. A day is written once GA reports its data as golden.
+type snapshotCollector struct {
+	conf      *snapshotConf
+	reports   *reportCollector
+	store     objectStore
+	lastCheck time.Time
+}
+
+func newSnapshotCollector(httpClient *http.Client, reports *reportCollector, sc *snapshotConf) (*snapshotCollector, error) {
+	if reports == nil {
+		return nil, errors.New("snapshots: no reports are configured")
+	}
+	store, err := newObjectStore(httpClient, sc.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("snapshots: %v", err)
+	}
+	return &snapshotCollector{conf: sc, reports: reports, store: store}, nil
+}
+
+func (s *snapshotCollector) name() string { return "snapshots" }
+
+// collect writes the missing snapshots of the last days, checked hourly.
+func (s *snapshotCollector) collect() error {
+	if time.Since(s.lastCheck) < time.Hour {
+		return nil
+	}
+	s.lastCheck = time.Now()
+
+	selected := make(map[string]bool)
+	for _, name := range s.conf.Reports {
+		selected[name] = true
+	}
+	var errs []string
+	for _, rc := range s.reports.conf {
+		if len(selected) > 0 && !selected[rc.Name] {
+			continue
+		}
+		// Days are days of the view, unless its dates are in UTC.
+		now, err := s.reports.now(rc)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("report %s: %v", rc.Name, err))
+			continue
+		}
+		for d := s.conf.Days; d >= 1; d-- {
+			date := now.AddDate(0, 0, -d).Format("2006-01-02")
+			if err := s.snapshot(rc, date); err != nil {
+				errs = append(errs, fmt.Sprintf("report %s: %s: %v", rc.Name, date, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// snapshot writes the values of a report on a date, unless written already
+// or not golden yet.
+func (s *snapshotCollector) snapshot(rc *reportConf, date string) error {
+	key := fmt.Sprintf("%s/%s.%s", rc.Name, date, s.conf.Format)
+	if ok, err := s.store.exists(key); ok || err != nil {
+		return err
+	}
+
+	report, err := s.reports.query(rc, date, date)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"date", "report", "viewid"}
+	var metricTypes []string
+	var records [][]string
+	if report != nil {
+		if !report.Data.IsDataGolden {
+			return nil
+		}
+		header = append(header, report.ColumnHeader.Dimensions...)
+		for _, h := range report.ColumnHeader.MetricHeader.MetricHeaderEntries {
+			header = append(header, h.Name)
+			metricTypes = append(metricTypes, h.Type)
+		}
+		for _, row := range report.Data.Rows {
+			record := append([]string{date, rc.Name, rc.ViewID}, row.Dimensions...)
+			if len(row.Metrics) > 0 {
+				record = append(record, row.Metrics[0].Values...)
+			}
+			records = append(records, record)
+		}
+	}
+
+	var data []byte
+	var contentType string
+	if s.conf.Format == "parquet" {
+		data, err = snapshotParquet(header, metricTypes, records)
+		contentType = "application/vnd.apache.parquet"
+	} else {
+		data, err = snapshotCSV(header, records)
+		contentType = "text/csv"
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.store.put(key, data, contentType); err != nil {
+		return err
+	}
+	snapshotsWritten.WithLabelValues(rc.Name).Inc()
+	return nil
+}
+
+// snapshotCSV returns the rows of a snapshot as CSV.
+func snapshotCSV(header []string, records [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(header)
+	w.WriteAll(records)
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// snapshotParquet returns the rows of a snapshot as Parquet: the date is a
+// DATE, dimensions strings and metrics INT64 or DOUBLE after their type.
+func snapshotParquet(header, metricTypes []string, records [][]string) ([]byte, error) {
+	var w parquetWriter
+	w.dateColumn(header[0])
+	dimensions := len(header) - len(metricTypes)
+	for _, name := range header[1:dimensions] {
+		w.stringColumn(name)
+	}
+	for i, typ := range metricTypes {
+		if typ == "INTEGER" {
+			w.int64Column(header[dimensions+i])
+		} else {
+			w.doubleColumn(header[dimensions+i])
+		}
+	}
+	for _, record := range records {
+		if err := w.write(record); err != nil {
+			return nil, err
+		}
+	}
+	return w.bytes(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// tenantConf defines a client whose views are exposed on their own path.
+type tenantConf struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+}
+
+// tenant keeps the metrics of a tenant's views apart from everything else,
+// they are only served on /metrics/<name> to holders of the tenant token.
+type tenant struct {
+	*tenantConf
+}
+
+// newTenants builds the tenants from configuration, keyed by name.
+func newTenants(tcs []*tenantConf) map[string]*tenant {
+	tenants := make(map[string]*tenant)
+	for _, tc := range tcs {
+		if tc.Name == "" || strings.Contains(tc.Name, "/") {
+			panic(fmt.Sprintf("invalid tenant name %q", tc.Name))
+		}
+		if _, ok := tenants[tc.Name]; ok {
+			panic(fmt.Sprintf("duplicate tenant %q", tc.Name))
+		}
+		tenants[tc.Name] = &tenant{tenantConf: tc}
+	}
+
+	return tenants
+}
+
+// handler serves the registries of the tenant views, requiring the tenant token as a
+// bearer token when one is configured.
+func (t *tenant) handler() http.Handler {
+	h := promhttp.HandlerFor(viewsGatherer(t.Name), promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.authorize(w, r) {
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authorize checks the tenant token of a request, when one is configured,
+// replying 401 when it is missing or wrong.
+func (t *tenant) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if t.Token == "" {
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ganalytics"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
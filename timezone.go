@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+)
+
+// relativeDate matches the NdaysAgo dates of the Reporting API.
+var relativeDate = regexp.MustCompile(`^([0-9]+)daysAgo$`)
+
+// viewTimezones resolves the time zones of views from the Management API.
+// GA days, e.g. today and yesterday in a report, are days of the time zone
+// of the view.
+type viewTimezones struct {
+	as   *analytics.Service
+	info *prometheus.GaugeVec
+
+	mu     sync.Mutex
+	zones  map[string]*time.Location
+	listed time.Time
+}
+
+func newViewTimezones(as *analytics.Service) (*viewTimezones, error) {
+	z := &viewTimezones{
+		as: as,
+		info: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ga_view_timezone_info",
+			Help: "Time zone of a view, GA days are days of this time zone.",
+		}, []string{"viewid", "timezone"}),
+		zones: make(map[string]*time.Location),
+	}
+	if err := registerAll(z.info); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+func (z *viewTimezones) name() string { return "timezones" }
+
+// collect resolves the time zones of the views and reports, views added on
+// reload included. They are cached, only unknown views are looked up.
+func (z *viewTimezones) collect() error {
+	viewIDs := make(map[string]bool)
+	for _, v := range getViews() {
+		viewIDs[v.ViewID] = true
+	}
+	for _, rc := range config.Reports {
+		viewIDs[rc.ViewID] = true
+	}
+	var errs []string
+	for viewID := range viewIDs {
+		if _, err := z.location(viewID); err != nil {
+			errs = append(errs, fmt.Sprintf("view %s: %v", viewID, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// location returns the time zone of a view. The time zones of all views
+// accessible are listed at once, page by page, again at most hourly for
+// unknown views.
+func (z *viewTimezones) location(viewID string) (*time.Location, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if loc, ok := z.zones[viewID]; ok {
+		return loc, nil
+	}
+	if time.Since(z.listed) < time.Hour {
+		return nil, errors.New("time zone unknown, the view is not accessible")
+	}
+	z.listed = time.Now()
+
+	for start := int64(1); ; {
+		profiles, err := z.as.Management.Profiles.List("~all", "~all").StartIndex(start).MaxResults(1000).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range profiles.Items {
+			loc, err := time.LoadLocation(p.Timezone)
+			if err != nil {
+				continue
+			}
+			id := "ga:" + p.Id
+			z.zones[id] = loc
+			z.info.WithLabelValues(id, p.Timezone).Set(1)
+		}
+		start += int64(len(profiles.Items))
+		if len(profiles.Items) == 0 || start > profiles.TotalResults {
+			break
+		}
+	}
+
+	if loc, ok := z.zones[viewID]; ok {
+		return loc, nil
+	}
+	return nil, errors.New("time zone unknown, the view is not accessible")
+}
+
+// resolveDate returns the YYYY-MM-DD date of a relative Reporting API date,
+// today, yesterday or NdaysAgo, at now. Other dates are returned as is.
+func resolveDate(date string, now time.Time) string {
+	switch date {
+	case "today":
+		return now.Format("2006-01-02")
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	if m := relativeDate.FindStringSubmatch(date); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return now.AddDate(0, 0, -n).Format("2006-01-02")
+	}
+	return date
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jws"
+	"golang.org/x/oauth2/jwt"
+)
+
+// tokenRetries is the number of attempts to obtain an access token before
+// the refresh is reported as failed.
+const tokenRetries = 3
+
+var (
+	tokenRefreshFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_token_refresh_failures_total",
+		Help: "Failed attempts to obtain an OAuth2 access token.",
+	})
+	tokenExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_token_expiry_timestamp_seconds",
+		Help: "Expiry of the current OAuth2 access token.",
+	})
+	clockSkew = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_clock_skew_seconds",
+		Help: "Offset of the Google token endpoint clock from the local clock.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(tokenRefreshFailures, tokenExpiry, clockSkew)
+}
+
+// tokenSource exchanges a signed JWT for an access token like jwt.Config,
+// but dates the assertion by the token endpoint's clock. Google rejects
+// assertions issued in the future or more than an hour ago, which breaks
+// authentication on hosts whose clock drifted.
+type tokenSource struct {
+	conf   *jwt.Config
+	key    *rsa.PrivateKey
+	client *http.Client
+
+	mu      sync.Mutex
+	skew    time.Duration
+	lastErr error
+}
+
+// tokenResponse is the token endpoint reply, on success or failure.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func newTokenSource(conf *jwt.Config, client *http.Client) (*tokenSource, error) {
+	key, err := parseKey(conf.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &tokenSource{conf: conf, key: key, client: client}
+	addReadyCheck("token", ts.err)
+
+	return ts, nil
+}
+
+// parseKey parses a PEM encoded PKCS8 or PKCS1 RSA private key.
+func parseKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block != nil {
+		data = block.Bytes
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(data); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("private key should be a PEM or plain PKCS1 or PKCS8: %v", err)
+	}
+
+	return key, nil
+}
+
+// Token implements oauth2.TokenSource, retrying failed refreshes with the
+// clock skew observed on the previous attempt.
+func (ts *tokenSource) Token() (*oauth2.Token, error) {
+	var err error
+	for attempt := 0; attempt < tokenRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		var tok *oauth2.Token
+		if tok, err = ts.fetch(); err == nil {
+			tokenExpiry.Set(float64(tok.Expiry.Unix()))
+			ts.setErr(nil)
+			return tok, nil
+		}
+		tokenRefreshFailures.Inc()
+	}
+
+	err = fmt.Errorf("token refresh: %v", err)
+	ts.setErr(err)
+
+	return nil, err
+}
+
+// fetch signs an assertion and exchanges it for an access token.
+func (ts *tokenSource) fetch() (*oauth2.Token, error) {
+	ts.mu.Lock()
+	skew := ts.skew
+	ts.mu.Unlock()
+
+	// Backdated like jws does by default, to tolerate small drifts.
+	iat := time.Now().Add(skew).Add(-10 * time.Second)
+	claims := &jws.ClaimSet{
+		Iss:   ts.conf.Email,
+		Scope: strings.Join(ts.conf.Scopes, " "),
+		Aud:   ts.conf.TokenURL,
+		Iat:   iat.Unix(),
+		Exp:   iat.Add(time.Hour).Unix(),
+	}
+	header := &jws.Header{Algorithm: "RS256", Typ: "JWT", KeyID: ts.conf.PrivateKeyID}
+	assertion, err := jws.Encode(header, claims, ts.key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ts.client.PostForm(ts.conf.TokenURL, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		ts.mu.Lock()
+		ts.skew = date.Sub(time.Now()).Round(time.Second)
+		clockSkew.Set(ts.skew.Seconds())
+		ts.mu.Unlock()
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	if resp.StatusCode != http.StatusOK || tr.AccessToken == "" {
+		return nil, fmt.Errorf("%s: %s %s", resp.Status, tr.Error, tr.ErrorDescription)
+	}
+
+	return &oauth2.Token{
+		AccessToken: tr.AccessToken,
+		TokenType:   tr.TokenType,
+		Expiry:      time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func (ts *tokenSource) setErr(err error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.lastErr = err
+}
+
+// err returns the error of the last refresh, nil once a refresh succeeded.
+func (ts *tokenSource) err() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.lastErr
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// translitTable maps non-ASCII letters to Latin, covering Cyrillic, Greek
+// and the accented Latin letters of European languages.
+var translitTable = map[rune]string{
+	// Cyrillic, Russian and Ukrainian.
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+	// Greek.
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+	'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ό': "o", 'ύ': "y", 'ώ': "o",
+	// Latin with diacritics.
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i",
+	'î': "i", 'ï': "i", 'ð': "d", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o",
+	'õ': "o", 'ö': "oe", 'ø': "o", 'ù': "u", 'ú': "u", 'û': "u", 'ü': "ue",
+	'ý': "y", 'ÿ': "y", 'þ': "th", 'ß': "ss", 'ą': "a", 'ć': "c", 'č': "c",
+	'ď': "d", 'ę': "e", 'ě': "e", 'ğ': "g", 'ı': "i", 'ł': "l", 'ń': "n",
+	'ň': "n", 'ő': "o", 'œ': "oe", 'ř': "r", 'ś': "s", 'š': "s", 'ş': "s",
+	'ť': "t", 'ů': "u", 'ű': "u", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// transliterate converts s to ASCII. Letters without a Latin equivalent,
+// e.g. CJK, are spelled as their code point, u4e2d, so distinct values
+// still yield distinct names.
+func transliterate(s string) string {
+	var b []byte
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf:
+			b = append(b, byte(r))
+		case unicode.IsUpper(r):
+			if t, ok := translitTable[unicode.ToLower(r)]; ok {
+				if t != "" {
+					t = strings.ToUpper(t[:1]) + t[1:]
+				}
+				b = append(b, t...)
+				continue
+			}
+			b = append(b, fmt.Sprintf("u%04x", r)...)
+		default:
+			if t, ok := translitTable[r]; ok {
+				b = append(b, t...)
+				continue
+			}
+			if unicode.IsLetter(r) || unicode.IsNumber(r) {
+				b = append(b, fmt.Sprintf("u%04x", r)...)
+			}
+		}
+	}
+
+	return string(b)
+}
+
+// sanitizeLabelValue keeps label values in any script, only dropping
+// invalid UTF-8 and control characters.
+func sanitizeLabelValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
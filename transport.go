@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// resolverConf defines DNS servers used instead of the system resolver.
+type resolverConf struct {
+	// Servers are host:port addresses, tried in turn.
+	Servers []string `yaml:"servers"`
+	// Network is udp or tcp, udp by default.
+	Network string `yaml:"network"`
+}
+
+// outboundTLSConf defines the TLS settings of connections to Google APIs,
+// e.g. behind a TLS-inspecting proxy re-signing traffic.
+type outboundTLSConf struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system ones.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are a PEM client certificate and its key.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// tlsConfig returns the TLS config of outbound connections.
+func (tc *outboundTLSConf) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if tc.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificate found", tc.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if tc.CertFile != "" || tc.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// newTransport returns the transport of outbound connections to Google
+// APIs, through the configured proxy and resolver and with the configured
+// TLS settings.
+func (c *conf) newTransport() (*http.Transport, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if c.Resolver != nil && len(c.Resolver.Servers) > 0 {
+		dialer.Resolver = c.Resolver.newResolver()
+	}
+
+	tr := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if c.OutboundTLS != nil {
+		cfg, err := c.OutboundTLS.tlsConfig()
+		if err != nil {
+			return nil, fmt.Errorf("outbound_tls: %v", err)
+		}
+		tr.TLSClientConfig = cfg
+	}
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+		switch u.Scheme {
+		case "socks5", "http", "https":
+		default:
+			return nil, fmt.Errorf("proxy: unsupported scheme %q", u.Scheme)
+		}
+		// Through a SOCKS5 proxy host names are resolved by the proxy.
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	return tr, nil
+}
+
+// userAgentTransport identifies the exporter in the User-Agent of requests,
+// ahead of the API client's.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	ua := t.userAgent
+	if prev := req.Header.Get("User-Agent"); prev != "" {
+		ua += " " + prev
+	}
+	r.Header.Set("User-Agent", ua)
+	return t.base.RoundTrip(r)
+}
+
+// userAgent returns the User-Agent of outbound requests,
+// <application_name>/<version>.
+func (c *conf) userAgent() string {
+	name := c.ApplicationName
+	if name == "" {
+		name = defaultApplicationName
+	}
+	return fmt.Sprintf("%s/%s", name, version)
+}
+
+// newResolver returns a resolver querying the configured servers.
+func (rc *resolverConf) newResolver() *net.Resolver {
+	network := rc.Network
+	if network == "" {
+		network = "udp"
+	}
+	var next uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			server := rc.Servers[int(atomic.AddUint32(&next, 1)-1)%len(rc.Servers)]
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
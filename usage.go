@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// GA Reporting and RealTime APIs allow 50,000 requests per project per day.
+const defaultDailyQuota = 50000
+
+var (
+	startTime = time.Now()
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ga_exporter_api_requests_total",
+		Help: "GA API requests issued.",
+	}, []string{"viewid", "alias"})
+	projectedRequests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ga_exporter_projected_daily_requests",
+		Help: "Projected GA API requests per day, from the configured intervals or the observed usage.",
+	}, []string{"viewid", "alias", "basis"})
+	dailyQuota = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ga_exporter_daily_request_quota",
+		Help: "GA API requests per day allowed for the project.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(apiRequests, projectedRequests, dailyQuota)
+}
+
+// usage tracks the API requests issued for a view.
+type usage struct {
+	viewID   string
+	alias    string
+	requests uint64
+}
+
+// newUsage sets the configured projection of a view polling metrics every
+// interval seconds.
+func newUsage(viewID, alias string, metrics int, interval int) *usage {
+	if interval > 0 {
+		projectedRequests.WithLabelValues(viewID, alias, "config").Set(float64(metrics) * 86400 / float64(interval))
+	}
+	return &usage{viewID: viewID, alias: alias}
+}
+
+// request counts an issued request and updates the observed projection.
+func (u *usage) request() {
+	if u == nil {
+		return
+	}
+	n := atomic.AddUint64(&u.requests, 1)
+	apiRequests.WithLabelValues(u.viewID, u.alias).Inc()
+
+	// Extrapolating from the first seconds of uptime is meaningless, the
+	// observed rate is averaged over at least one interval.
+	elapsed := time.Since(startTime).Seconds()
+	if min := float64(config.Interval); elapsed < min {
+		elapsed = min
+	}
+	projectedRequests.WithLabelValues(u.viewID, u.alias, "observed").Set(float64(n) * 86400 / elapsed)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/analytics/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// rtColumns lists the RealTime API columns and their type. The Metadata
+// API only describes Core Reporting (ga:) columns.
+var rtColumns = map[string]string{
+	"rt:activeUsers":            "METRIC",
+	"rt:pageviews":              "METRIC",
+	"rt:screenViews":            "METRIC",
+	"rt:totalEvents":            "METRIC",
+	"rt:goalXXStarts":           "METRIC",
+	"rt:goalStartsAll":          "METRIC",
+	"rt:goalXXCompletions":      "METRIC",
+	"rt:goalCompletionsAll":     "METRIC",
+	"rt:userType":               "DIMENSION",
+	"rt:minutesAgo":             "DIMENSION",
+	"rt:referralPath":           "DIMENSION",
+	"rt:campaign":               "DIMENSION",
+	"rt:source":                 "DIMENSION",
+	"rt:medium":                 "DIMENSION",
+	"rt:trafficType":            "DIMENSION",
+	"rt:keyword":                "DIMENSION",
+	"rt:goalId":                 "DIMENSION",
+	"rt:browser":                "DIMENSION",
+	"rt:browserVersion":         "DIMENSION",
+	"rt:operatingSystem":        "DIMENSION",
+	"rt:operatingSystemVersion": "DIMENSION",
+	"rt:deviceCategory":         "DIMENSION",
+	"rt:mobileDeviceBranding":   "DIMENSION",
+	"rt:mobileDeviceModel":      "DIMENSION",
+	"rt:country":                "DIMENSION",
+	"rt:region":                 "DIMENSION",
+	"rt:city":                   "DIMENSION",
+	"rt:latitude":               "DIMENSION",
+	"rt:longitude":              "DIMENSION",
+	"rt:pagePath":               "DIMENSION",
+	"rt:pageTitle":              "DIMENSION",
+	"rt:appName":                "DIMENSION",
+	"rt:appVersion":             "DIMENSION",
+	"rt:screenName":             "DIMENSION",
+	"rt:eventAction":            "DIMENSION",
+	"rt:eventCategory":          "DIMENSION",
+	"rt:eventLabel":             "DIMENSION",
+	"rt:hostname":               "DIMENSION",
+}
+
+var goalIndex = regexp.MustCompile(`^rt:goal\d+`)
+
+// configProblem is an invalid column or metric/dimension combination, or
+// a filter of the view the API rejects.
+type configProblem struct {
+	ViewID     string
+	Metric     string
+	Dimensions string
+	Filters    string
+	Problem    string
+	Suggestion string
+}
+
+func (p configProblem) String() string {
+	s := fmt.Sprintf("view %s: %s", p.ViewID, p.Metric)
+	if p.Dimensions != "" {
+		s += fmt.Sprintf(" by %s", p.Dimensions)
+	}
+	if p.Filters != "" {
+		s += fmt.Sprintf(" filtered by %s", p.Filters)
+	}
+	s += fmt.Sprintf(": %s", p.Problem)
+	if p.Suggestion != "" {
+		s += fmt.Sprintf(" (%s)", p.Suggestion)
+	}
+	return s
+}
+
+// validateConfig checks every configured metric and its dimensions. Columns
+// are looked up first, ga: columns in the Metadata API, then each
+// combination is probed with a single row RealTime query, with the hostname
+// filter of the view, as the API is the only authority on which pairs are
+// allowed. A query only rejected with its filter is a problem of the
+// filter, not of the metric. A non-nil error means the
+// validation itself could not be completed.
+func validateConfig(as *analytics.Service, rts *analytics.DataRealtimeService) ([]configProblem, error) {
+	var gaColumns map[string]*analytics.Column
+	var problems []configProblem
+
+	for _, v := range views {
+		for _, key := range v.queries() {
+			metric, dimensions := v.baseMetric(key), v.getDimensions(key)
+			p := configProblem{ViewID: v.ViewID, Metric: key, Dimensions: dimensions}
+
+			columns := make(map[string]string)
+			for _, m := range strings.Split(metric, ",") {
+				columns[m] = "METRIC"
+			}
+			if dimensions != "" {
+				for _, d := range strings.Split(dimensions, ",") {
+					columns[d] = "DIMENSION"
+				}
+			}
+
+			valid := true
+			for column, want := range columns {
+				if strings.HasPrefix(column, "ga:") && gaColumns == nil {
+					var err error
+					if gaColumns, err = metadataColumns(as); err != nil {
+						return nil, err
+					}
+				}
+				if problem, suggestion := checkColumn(column, want, gaColumns); problem != "" {
+					p.Problem, p.Suggestion = problem, suggestion
+					problems = append(problems, p)
+					valid = false
+				}
+			}
+			if !valid {
+				continue
+			}
+
+			filters := v.hostnameFilter()
+			gerr, err := probe(rts, v.ViewID, metric, dimensions, filters)
+			if err != nil {
+				return nil, fmt.Errorf("view %s: %v", v.displayName(), err)
+			}
+			if gerr != nil && filters != "" {
+				unfiltered, err := probe(rts, v.ViewID, metric, dimensions, "")
+				if err != nil {
+					return nil, fmt.Errorf("view %s: %v", v.displayName(), err)
+				}
+				if unfiltered == nil {
+					p.Filters = filters
+					p.Problem = fmt.Sprintf("valid_hostnames filter rejected by the API: %s", gerr.Message)
+					p.Suggestion = "fix or remove valid_hostnames"
+					problems = append(problems, p)
+					continue
+				}
+				gerr = unfiltered
+			}
+			if gerr != nil {
+				p.Problem = fmt.Sprintf("rejected by the API: %s", gerr.Message)
+				p.Suggestion = "query the metric with fewer or other dimensions"
+				problems = append(problems, p)
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// probe issues a single row RealTime query, returning the API error when it
+// is rejected as invalid and any other error as err.
+func probe(rts *analytics.DataRealtimeService, viewID, metric, dimensions, filters string) (*googleapi.Error, error) {
+	getc := rts.Get(viewID, metric).MaxResults(1)
+	if dimensions != "" {
+		getc.Dimensions(dimensions)
+	}
+	if filters != "" {
+		getc.Filters(filters)
+	}
+	_, err := getc.Do()
+	if err == nil {
+		return nil, nil
+	}
+	if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == 400 {
+		return gerr, nil
+	}
+	return nil, err
+}
+
+// metadataColumns fetches the Core Reporting columns from the Metadata API.
+func metadataColumns(as *analytics.Service) (map[string]*analytics.Column, error) {
+	cols, err := as.Metadata.Columns.List("ga").Do()
+	if err != nil {
+		return nil, fmt.Errorf("metadata API: %v", err)
+	}
+
+	columns := make(map[string]*analytics.Column)
+	for _, c := range cols.Items {
+		columns[c.Id] = c
+	}
+
+	return columns, nil
+}
+
+// checkColumn returns a problem and suggested alternative for a column
+// expected to be of type want, or empty strings when it is valid.
+func checkColumn(column, want string, gaColumns map[string]*analytics.Column) (string, string) {
+	if strings.HasPrefix(column, "ga:") {
+		rt := "rt:" + strings.TrimPrefix(column, "ga:")
+		suggestion := ""
+		if _, ok := rtColumns[goalIndex.ReplaceAllString(rt, "rt:goalXX")]; ok {
+			suggestion = fmt.Sprintf("use %s", rt)
+		}
+		c, ok := gaColumns[column]
+		switch {
+		case !ok:
+			return "unknown column", suggest(column, gaColumns)
+		case c.Attributes["status"] == "DEPRECATED" && c.Attributes["replacedBy"] != "":
+			return "deprecated Core Reporting column, the RealTime API only accepts rt: columns", fmt.Sprintf("replaced by %s", c.Attributes["replacedBy"])
+		default:
+			return "Core Reporting column, the RealTime API only accepts rt: columns", suggestion
+		}
+	}
+
+	kind, ok := rtColumns[goalIndex.ReplaceAllString(column, "rt:goalXX")]
+	if !ok {
+		candidates := make(map[string]*analytics.Column)
+		for c := range rtColumns {
+			candidates[c] = nil
+		}
+		return "unknown RealTime column", suggest(column, candidates)
+	}
+	if kind != want {
+		return fmt.Sprintf("%s is a %s, not a %s", column, strings.ToLower(kind), strings.ToLower(want)), ""
+	}
+
+	return "", ""
+}
+
+// suggest returns the closest column names to column.
+func suggest(column string, candidates map[string]*analytics.Column) string {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	var matches []match
+	max := len(column)/3 + 1
+	for name := range candidates {
+		if d := levenshtein(strings.ToLower(column), strings.ToLower(name)); d <= max {
+			matches = append(matches, match{name, d})
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+	var names []string
+	for i := 0; i < len(matches) && i < 3; i++ {
+		names = append(names, matches[i].name)
+	}
+
+	return fmt.Sprintf("did you mean %s?", strings.Join(names, ", "))
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(n int, ns ...int) int {
+	for _, m := range ns {
+		if m < n {
+			n = m
+		}
+	}
+	return n
+}
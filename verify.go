@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/analytics/v3"
+	"google.golang.org/api/analyticsdata/v1beta"
+)
+
+// verifyCheck is the outcome of a check of verify.
+type verifyCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// verifyReport is the outcome of verify, passed when every check did.
+type verifyReport struct {
+	Passed bool          `json:"passed"`
+	Checks []verifyCheck `json:"checks"`
+}
+
+func (r *verifyReport) add(name string, err error, detail string) {
+	c := verifyCheck{Name: name, Passed: err == nil, Detail: detail}
+	if err != nil {
+		c.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// verify queries a test view, and optionally a GA4 property, with the
+// credentials of the config: values must parse, be exported and pass the
+// lint, and the quota left must allow the next requests. It is the smoke
+// test of a release against the live APIs. It returns the exit status.
+func verify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	viewID := fs.String("view", "", "Test view queried with the RealTime API, e.g. ga:123456789.")
+	property := fs.String("property", "", "Test GA4 property queried with the Data API, none when empty.")
+	metrics := fs.String("metrics", "rt:activeUsers", "RealTime metrics queried, comma separated.")
+	dimensions := fs.String("dimensions", "", "RealTime dimensions of the query, comma separated.")
+	format := fs.String("format", "text", "Report format, text or json.")
+	fs.Parse(args)
+	if *viewID == "" {
+		fmt.Fprintln(os.Stderr, "verify: -view is required")
+		return 2
+	}
+
+	httpClient := newHTTPClient()
+	var r verifyReport
+	verifyRealtime(&r, httpClient, *viewID, *metrics, *dimensions)
+	if *property != "" {
+		verifyGA4(&r, httpClient, strings.TrimPrefix(*property, "properties/"))
+	}
+
+	r.Passed = true
+	for _, c := range r.Checks {
+		r.Passed = r.Passed && c.Passed
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(r)
+	} else {
+		for _, c := range r.Checks {
+			status := "PASS"
+			if !c.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("%s %s: %s\n", status, c.Name, c.Detail)
+		}
+		if r.Passed {
+			fmt.Println("verify PASS")
+		} else {
+			fmt.Println("verify FAIL")
+		}
+	}
+	if !r.Passed {
+		return 1
+	}
+	return 0
+}
+
+// verifyRealtime queries the metrics of a view and exports the response
+// as a view of the config would.
+func verifyRealtime(r *verifyReport, httpClient *http.Client, viewID, metrics, dimensions string) {
+	as, err := analytics.New(httpClient)
+	if err != nil {
+		r.add("realtime query", err, "")
+		return
+	}
+	getc := analytics.NewDataRealtimeService(as).Get(viewID, metrics)
+	if dimensions != "" {
+		getc.Dimensions(dimensions)
+	}
+	m, err := getc.Do()
+	if err != nil {
+		r.add("realtime query", fmt.Errorf("category=%s: %v", errorCategory(err), err), "")
+		return
+	}
+	r.add("realtime query", nil, fmt.Sprintf("HTTP %d, %d rows", m.HTTPStatusCode, len(m.Rows)))
+
+	detail, err := checkQuotaHeaders(m.Header)
+	r.add("realtime quota headers", err, detail)
+
+	var bad []string
+	for i, h := range m.ColumnHeaders {
+		if h.ColumnType != "METRIC" {
+			continue
+		}
+		for _, row := range m.Rows {
+			if _, err := strconv.ParseFloat(row[i], 64); err != nil {
+				bad = append(bad, fmt.Sprintf("%s %q", h.Name, row[i]))
+			}
+		}
+		if _, err := strconv.ParseFloat(m.TotalsForAllResults[h.Name], 64); err != nil {
+			bad = append(bad, fmt.Sprintf("%s total %q", h.Name, m.TotalsForAllResults[h.Name]))
+		}
+	}
+	if len(bad) > 0 {
+		r.add("realtime values", fmt.Errorf("unparsable values: %s", strings.Join(bad, ", ")), "")
+	} else {
+		r.add("realtime values", nil, "all values parse")
+	}
+
+	// Exported by a view of its own, so the metrics of the config don't
+	// interfere.
+	vc := &viewConf{ViewID: viewID, Metrics: []string{metrics}}
+	if dimensions != "" {
+		vc.Dimensions = []map[string][]string{{metrics: strings.Split(dimensions, ",")}}
+	}
+	v := newDryRunView(vc, nameTemplate)
+	if err := v.export(metrics, m); err != nil {
+		r.add("realtime export", err, "")
+		return
+	}
+	families, err := scrape(prometheus.Gatherers{v.registry})
+	if err != nil {
+		r.add("realtime export", err, "")
+		return
+	}
+	var missing []string
+	for _, metric := range strings.Split(metrics, ",") {
+		name, err := v.metricName(metric)
+		if err != nil {
+			r.add("realtime export", err, "")
+			return
+		}
+		if _, ok := families[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		r.add("realtime export", fmt.Errorf("not exported: %s", strings.Join(missing, ", ")), "")
+	} else {
+		r.add("realtime export", nil, fmt.Sprintf("%d metric families", len(families)))
+	}
+	// Only the GA metrics, the exporter's own are not verified.
+	for name := range families {
+		if strings.HasPrefix(name, "ga_exporter_") {
+			delete(families, name)
+		}
+	}
+	if problems := lintMetrics(families); len(problems) > 0 {
+		r.add("realtime lint", fmt.Errorf("%s", strings.Join(problems, "; ")), "")
+	} else {
+		r.add("realtime lint", nil, "no problems")
+	}
+}
+
+// verifyGA4 queries the active users of a GA4 property with its quota, every
+// quota must have tokens or requests left.
+func verifyGA4(r *verifyReport, httpClient *http.Client, property string) {
+	svc, err := analyticsdata.New(httpClient)
+	if err != nil {
+		r.add("ga4 query", err, "")
+		return
+	}
+	resp, err := svc.Properties.RunRealtimeReport("properties/"+property, &analyticsdata.RunRealtimeReportRequest{
+		Metrics:             []*analyticsdata.Metric{{Name: "activeUsers"}},
+		ReturnPropertyQuota: true,
+	}).Do()
+	if err != nil {
+		r.add("ga4 query", fmt.Errorf("category=%s: %v", errorCategory(err), err), "")
+		return
+	}
+	r.add("ga4 query", nil, fmt.Sprintf("HTTP %d, %d rows", resp.HTTPStatusCode, len(resp.Rows)))
+
+	var bad []string
+	for _, row := range resp.Rows {
+		for _, mv := range row.MetricValues {
+			if _, err := strconv.ParseFloat(mv.Value, 64); err != nil {
+				bad = append(bad, strconv.Quote(mv.Value))
+			}
+		}
+	}
+	if len(bad) > 0 {
+		r.add("ga4 values", fmt.Errorf("unparsable values: %s", strings.Join(bad, ", ")), "")
+	} else {
+		r.add("ga4 values", nil, "all values parse")
+	}
+
+	if resp.PropertyQuota == nil {
+		r.add("ga4 quota", fmt.Errorf("no property quota returned"), "")
+		return
+	}
+	statuses := ga4QuotaStatuses(resp.PropertyQuota)
+	quotas := make([]string, 0, len(statuses))
+	for quota := range statuses {
+		quotas = append(quotas, quota)
+	}
+	sort.Strings(quotas)
+	var left, exhausted []string
+	for _, quota := range quotas {
+		s := fmt.Sprintf("%s %d", quota, statuses[quota].Remaining)
+		left = append(left, s)
+		if statuses[quota].Remaining <= 0 {
+			exhausted = append(exhausted, s)
+		}
+	}
+	if len(exhausted) > 0 {
+		r.add("ga4 quota", fmt.Errorf("exhausted: %s", strings.Join(exhausted, ", ")), "")
+	} else {
+		r.add("ga4 quota", nil, "remaining: "+strings.Join(left, ", "))
+	}
+}
+
+// checkQuotaHeaders checks the rate limit headers of a response, Google APIs
+// only send them when throttling: a Retry-After or no X-RateLimit-Remaining
+// left fail. It returns the headers seen.
+func checkQuotaHeaders(h http.Header) (string, error) {
+	var seen []string
+	for name := range h {
+		l := strings.ToLower(name)
+		if strings.Contains(l, "ratelimit") || strings.Contains(l, "quota") || l == "retry-after" {
+			seen = append(seen, name+": "+h.Get(name))
+		}
+	}
+	sort.Strings(seen)
+
+	if ra := h.Get("Retry-After"); ra != "" {
+		return "", fmt.Errorf("throttled, Retry-After %s", ra)
+	}
+	if remaining := h.Get("X-RateLimit-Remaining"); remaining != "" {
+		if n, err := strconv.Atoi(remaining); err != nil || n <= 0 {
+			return "", fmt.Errorf("X-RateLimit-Remaining %s", remaining)
+		}
+	}
+	if len(seen) == 0 {
+		return "none sent, not throttled", nil
+	}
+	return strings.Join(seen, ", "), nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// victoriaMetricsConf defines the VictoriaMetrics the values collected every
+// cycle are pushed to, for environments without a scrape path to the
+// exporter.
+type victoriaMetricsConf struct {
+	// URL of the JSON line import endpoint, e.g.
+	// http://victoriametrics:8428/api/v1/import.
+	URL string `yaml:"url"`
+	// ExtraLabels are added to every series, e.g. the environment.
+	ExtraLabels map[string]string `yaml:"extra_labels"`
+	// Username and Password authenticate with basic auth, BearerToken with
+	// a bearer token.
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	BearerToken string `yaml:"bearer_token"`
+}
+
+var (
+	vmSamples = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_victoriametrics_samples_total",
+		Help: "Samples pushed to VictoriaMetrics.",
+	})
+	vmFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_victoriametrics_failed_pushes_total",
+		Help: "Pushes to VictoriaMetrics that failed.",
+	})
+	vmSkipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ga_exporter_victoriametrics_skipped_pushes_total",
+		Help: "Pushes to VictoriaMetrics skipped because the previous one was still in flight.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(vmSamples, vmFailures, vmSkipped)
+}
+
+// vmLine is a series of the JSON line import format.
+type vmLine struct {
+	Metric     map[string]string `json:"metric"`
+	Values     []float64         `json:"values"`
+	Timestamps []int64           `json:"timestamps"`
+}
+
+// vmSink pushes the values of the views collected in a cycle, with the
+// exporter metrics, to the VictoriaMetrics import API.
+type vmSink struct {
+	conf   *victoriaMetricsConf
+	client *http.Client
+	// mu is held by the push in flight, the pushes of the cycles ending
+	// meanwhile are skipped rather than piling up.
+	mu sync.Mutex
+}
+
+func newVMSink(vc *victoriaMetricsConf) *vmSink {
+	return &vmSink{conf: vc, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// write pushes the current values of the metrics of views and of the
+// exporter, at ts, unless a push is in flight. Failures are logged, the
+// next cycle pushes again.
+func (s *vmSink) write(ts time.Time, vs []*view) {
+	if !s.mu.TryLock() {
+		vmSkipped.Inc()
+		return
+	}
+	defer s.mu.Unlock()
+
+	gs := prometheus.Gatherers{prometheus.DefaultGatherer}
+	for _, v := range vs {
+		gs = append(gs, v.registry)
+	}
+	mfs, err := gs.Gather()
+	if err != nil {
+		log.Printf("victoriametrics: %v", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	ms := ts.UnixNano() / int64(time.Millisecond)
+	n := 0
+	for _, mf := range mfs {
+		for _, fs := range flatten(mf) {
+			// NaN and infinities can't be encoded, VictoriaMetrics takes
+			// NaN as a staleness marker anyway.
+			if math.IsNaN(fs.value) || math.IsInf(fs.value, 0) {
+				continue
+			}
+			metric := map[string]string{"__name__": fs.name}
+			for name, value := range s.conf.ExtraLabels {
+				metric[name] = value
+			}
+			for name, value := range fs.labels {
+				metric[name] = value
+			}
+			if err := enc.Encode(vmLine{Metric: metric, Values: []float64{fs.value}, Timestamps: []int64{ms}}); err != nil {
+				log.Printf("victoriametrics: %v", err)
+				vmFailures.Inc()
+				return
+			}
+			n++
+		}
+	}
+	gz.Close()
+
+	if err := s.post(&buf); err != nil {
+		log.Printf("victoriametrics: %v", err)
+		vmFailures.Inc()
+		return
+	}
+	vmSamples.Add(float64(n))
+}
+
+// post sends gzipped JSON lines to the import endpoint.
+func (s *vmSink) post(body io.Reader) error {
+	req, err := http.NewRequest(http.MethodPost, s.conf.URL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	switch {
+	case s.conf.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+s.conf.BearerToken)
+	case s.conf.Username != "":
+		req.SetBasicAuth(s.conf.Username, s.conf.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// flatSample is a sample of a metric family, as exposed in the text format:
+// histograms and summaries are expanded into their bucket or quantile, sum
+// and count series.
+type flatSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// flatten returns the samples of a metric family.
+func flatten(mf *dto.MetricFamily) []flatSample {
+	var samples []flatSample
+	for _, m := range mf.Metric {
+		labels := func(extra ...string) map[string]string {
+			l := make(map[string]string, len(m.Label)+1)
+			for _, lp := range m.Label {
+				l[lp.GetName()] = lp.GetValue()
+			}
+			for i := 0; i+1 < len(extra); i += 2 {
+				l[extra[i]] = extra[i+1]
+			}
+			return l
+		}
+		switch {
+		case m.Gauge != nil:
+			samples = append(samples, flatSample{mf.GetName(), labels(), m.Gauge.GetValue()})
+		case m.Counter != nil:
+			samples = append(samples, flatSample{mf.GetName(), labels(), m.Counter.GetValue()})
+		case m.Untyped != nil:
+			samples = append(samples, flatSample{mf.GetName(), labels(), m.Untyped.GetValue()})
+		case m.Histogram != nil:
+			h := m.Histogram
+			for _, b := range h.Bucket {
+				le := strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)
+				samples = append(samples, flatSample{mf.GetName() + "_bucket", labels("le", le), float64(b.GetCumulativeCount())})
+			}
+			samples = append(samples,
+				flatSample{mf.GetName() + "_bucket", labels("le", "+Inf"), float64(h.GetSampleCount())},
+				flatSample{mf.GetName() + "_sum", labels(), h.GetSampleSum()},
+				flatSample{mf.GetName() + "_count", labels(), float64(h.GetSampleCount())})
+		case m.Summary != nil:
+			sm := m.Summary
+			for _, q := range sm.Quantile {
+				quantile := strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)
+				samples = append(samples, flatSample{mf.GetName(), labels("quantile", quantile), q.GetValue()})
+			}
+			samples = append(samples,
+				flatSample{mf.GetName() + "_sum", labels(), sm.GetSampleSum()},
+				flatSample{mf.GetName() + "_count", labels(), float64(sm.GetSampleCount())})
+		}
+	}
+	return samples
+}
@@ -0,0 +1,654 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/analytics/v3"
+)
+
+// Defaults for the per view request isolation.
+const (
+	defaultRateLimit        = 10
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 300
+)
+
+// viewConf defines a single GA view and the metrics collected from it.
+type viewConf struct {
+	ViewID string `yaml:"viewid"`
+	Tenant string `yaml:"tenant"`
+	// Alias is a human-friendly name of the view, shown with its ID in
+	// logs and errors, and labelling the exporter's own metrics of the
+	// view.
+	Alias      string                `yaml:"alias"`
+	Metrics    []string              `yaml:"metrics"`
+	Dimensions []map[string][]string `yaml:"dimensions"`
+	// Breakdowns export metrics by other dimensions, each under its own
+	// name.
+	Breakdowns map[string][]*breakdownConf `yaml:"breakdowns"`
+	// Mappings declares, per metric, how response columns become samples.
+	Mappings map[string]*mapping `yaml:"mappings"`
+	// Bounds clamp or reject implausible values, per metric.
+	Bounds map[string]*boundsConf `yaml:"bounds"`
+	// Precision rounds the values, per metric.
+	Precision map[string]*precisionConf `yaml:"precision"`
+	// Anomaly exports scores of metrics against their usual value for the
+	// hour of the week.
+	Anomaly *anomalyConf `yaml:"anomaly"`
+	// ChangePerMinute exports the change of metrics between consecutive
+	// polls, per minute, to alert on sudden traffic drops.
+	ChangePerMinute []string `yaml:"change_per_minute"`
+	// Content enables the active users by page collector for the view.
+	Content *contentConf `yaml:"content"`
+	// Campaigns enables the campaign attribution collector for the view.
+	Campaigns *campaignConf `yaml:"campaigns"`
+	// DeviceSplit enables the active users by device category collector
+	// for the view, with their ratios.
+	DeviceSplit bool `yaml:"device_split"`
+	// GoalValues enables the realtime goal value collector for the view.
+	GoalValues *goalValueConf `yaml:"goal_values"`
+	// Schedules override the polling interval of metrics by time of day.
+	Schedules map[string][]*scheduleRule `yaml:"schedules"`
+	// ValidHostnames restricts queries to traffic of these hostnames,
+	// discarding ghost spam. Defaults to the global valid_hostnames.
+	ValidHostnames []string `yaml:"valid_hostnames"`
+	// Filters keep or drop rows by dimension value, after the query.
+	Filters map[string]*valueFilter `yaml:"filters"`
+	// ExpectedValues lists, per dimension column, label values exported as
+	// 0 when missing from a response.
+	ExpectedValues map[string][]string `yaml:"expected_values"`
+	// IncludeNotSet exports "(not set)" rows, which are dropped by default.
+	IncludeNotSet bool `yaml:"include_not_set"`
+	// ReportDropped exports the value of dropped rows per metric, so the
+	// breakdown can be reconciled with the total.
+	ReportDropped bool `yaml:"report_dropped"`
+
+	// RateLimit caps API requests per second issued for the view.
+	RateLimit float64 `yaml:"rate_limit"`
+	// BreakerThreshold consecutive failures stop queries to the view for
+	// BreakerCooldown seconds.
+	BreakerThreshold int `yaml:"breaker_threshold"`
+	BreakerCooldown  int `yaml:"breaker_cooldown"`
+	// Retry is the policy of failed queries, not retried by default.
+	Retry *retryConf `yaml:"retry"`
+}
+
+// view holds the registered metrics of a single GA view. Every view has its
+// own registry, rate limiter and circuit breaker, so quota exhaustion or a
+// label explosion in one view cannot affect the others.
+type view struct {
+	*viewConf
+	registry     *prometheus.Registry
+	warm         *warmGatherer
+	limiter      *rate.Limiter
+	breaker      *breaker
+	usage        *usage
+	nameTemplate *template.Template
+	promGauge    map[string]prometheus.Gauge
+	gaugeSet     map[string]bool
+	mu           sync.Mutex
+	promGaugeVec map[string]*prometheus.GaugeVec
+	vecUsed      map[string]time.Time
+	series       map[string]map[string]bool
+	seriesCount  *prometheus.GaugeVec
+	limit        *seriesLimit
+	promTotal    map[string]prometheus.Gauge
+	nextRun      map[string]time.Time
+	nextRunTime  *prometheus.GaugeVec
+	skippedRuns  *prometheus.CounterVec
+	lastSuccess  map[string]time.Time
+	droppedValue *prometheus.GaugeVec
+	contentVec   *prometheus.GaugeVec
+	campaignVec  *prometheus.GaugeVec
+	deviceSplit  *deviceSplit
+	goalValueVec *prometheus.GaugeVec
+	diskCache    *diskCache
+	// stale flags metrics exported from the disk cache.
+	stale *prometheus.GaugeVec
+	// unexpectedRows counts rows not exported in strict mode.
+	unexpectedRows *prometheus.CounterVec
+	// filteredRows counts rows dropped by the filters.
+	filteredRows *prometheus.CounterVec
+	// guards hold the spike rejection state of bounded series.
+	guards          map[string]*seriesGuard
+	rejectedValue   *prometheus.GaugeVec
+	rejectedSamples *prometheus.CounterVec
+	// baselines of the series with anomaly scores.
+	baselines map[string]*baseline
+	zscoreVec map[string]*prometheus.GaugeVec
+	// lastPolls of the series with a change rate.
+	lastPolls map[string]lastPoll
+	changeVec map[string]*prometheus.GaugeVec
+	// restoring is set while the disk cache is exported.
+	restoring bool
+	// disabled metrics, whose columns the API rejected.
+	disabled    map[string]bool
+	disabledVec *prometheus.GaugeVec
+	// ctx replaces the cycle context of the queries, of canary views.
+	ctx context.Context
+}
+
+// newView registers all metrics of a view as Prometheus Gauge.
+func newView(vc *viewConf) *view {
+	v := newDryRunView(vc, nameTemplate)
+	v.breaker = newBreaker(vc.ViewID, vc.Alias, vc.BreakerThreshold, time.Duration(vc.BreakerCooldown)*time.Second)
+	v.usage = newUsage(vc.ViewID, vc.Alias, len(vc.queries()), config.Interval)
+	v.limit = globalSeriesLimit
+	v.diskCache = responseDiskCache
+	v.diskCache.restore(v)
+
+	return v
+}
+
+// newDryRunView returns a view without circuit breaker and usage tracking,
+// whose registry is not exposed.
+func newDryRunView(vc *viewConf, t *template.Template) *view {
+	vc.setDefaults()
+	v := &view{
+		viewConf:     vc,
+		registry:     prometheus.NewRegistry(),
+		limiter:      rate.NewLimiter(rate.Limit(vc.RateLimit), int(vc.RateLimit)+1),
+		nameTemplate: t,
+		promGauge:    make(map[string]prometheus.Gauge),
+		gaugeSet:     make(map[string]bool),
+		promGaugeVec: make(map[string]*prometheus.GaugeVec),
+		vecUsed:      make(map[string]time.Time),
+		series:       make(map[string]map[string]bool),
+		promTotal:    make(map[string]prometheus.Gauge),
+		nextRun:      make(map[string]time.Time),
+		lastSuccess:  make(map[string]time.Time),
+		guards:       make(map[string]*seriesGuard),
+		baselines:    make(map[string]*baseline),
+		zscoreVec:    make(map[string]*prometheus.GaugeVec),
+		lastPolls:    make(map[string]lastPoll),
+		changeVec:    make(map[string]*prometheus.GaugeVec),
+		disabled:     make(map[string]bool),
+	}
+
+	v.warm = &warmGatherer{live: v.registry}
+
+	if vc.ReportDropped {
+		v.droppedValue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "ga_breakdown_dropped_value",
+			Help:        "Value of the rows left out of a dimensioned metric breakdown.",
+			ConstLabels: v.constLabels(),
+		}, []string{"metric"})
+		v.registry.MustRegister(v.droppedValue)
+	}
+
+	v.seriesCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_exporter_series_count",
+		Help:        "Label combinations exported for a metric.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric"})
+	v.registry.MustRegister(v.seriesCount)
+
+	v.nextRunTime, v.skippedRuns = newSchedulerVecs(v)
+	v.registry.MustRegister(v.nextRunTime, v.skippedRuns)
+
+	v.disabledVec = newDisabledVec(v)
+	v.registry.MustRegister(v.disabledVec)
+
+	v.stale = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_exporter_stale",
+		Help:        "Whether a metric holds values cached on disk by a previous process, until fetched again.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric"})
+	v.registry.MustRegister(v.stale)
+
+	v.unexpectedRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "ga_exporter_unexpected_rows_total",
+		Help:        "Rows of columns not declared in the config, not exported with strict_metrics.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric"})
+	v.registry.MustRegister(v.unexpectedRows)
+
+	if len(vc.Filters) > 0 {
+		v.filteredRows = newFilteredRows(v)
+		v.registry.MustRegister(v.filteredRows)
+	}
+	if len(vc.Bounds) > 0 {
+		v.rejectedValue, v.rejectedSamples = newRejectedVecs(v)
+		v.registry.MustRegister(v.rejectedValue, v.rejectedSamples)
+	}
+	if vc.Content != nil {
+		v.contentVec = newContentVec(v)
+		v.registry.MustRegister(v.contentVec)
+	}
+	if vc.Campaigns != nil {
+		v.campaignVec = newCampaignVec(v)
+		v.registry.MustRegister(v.campaignVec)
+	}
+	if vc.DeviceSplit {
+		v.deviceSplit = newDeviceSplit(v)
+		v.registry.MustRegister(v.deviceSplit)
+	}
+	if vc.GoalValues != nil {
+		v.goalValueVec = newGoalValueVec(v)
+		v.registry.MustRegister(v.goalValueVec)
+	}
+
+	window := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "ga_exporter_data_window_seconds",
+		Help:        "Time window covered by the RealTime API values of a metric.",
+		ConstLabels: v.selfLabels(),
+	}, []string{"metric"})
+	v.registry.MustRegister(window)
+	for _, metrics := range vc.Metrics {
+		for _, metric := range strings.Split(metrics, ",") {
+			window.WithLabelValues(metric).Set(realtimeWindow(metric).Seconds())
+		}
+	}
+
+	// A query can request several metrics, each is exported on its own.
+	// Gauges are registered on their first value, until then the values
+	// handed off by the previous process are served. Dimensioned metrics
+	// are registered as GaugeVec once their labels are known.
+	for _, metrics := range vc.Metrics {
+		if v.getDimensions(metrics) != "" {
+			continue
+		}
+		for _, metric := range strings.Split(metrics, ",") {
+			if _, ok := v.promGauge[metric]; ok {
+				continue
+			}
+			name, err := v.metricName(metric)
+			if err != nil {
+				log.Printf("view %s: %v", vc.displayName(), err)
+				continue
+			}
+			v.promGauge[metric] = prometheus.NewGauge(prometheus.GaugeOpts{
+				Name:        name,
+				Help:        fmt.Sprintf("Google Analytics %s", metric),
+				ConstLabels: v.constLabels(),
+			})
+		}
+	}
+
+	return v
+}
+
+// setDefaults sets the defaults of unset view settings.
+func (vc *viewConf) setDefaults() {
+	if vc.RateLimit <= 0 {
+		vc.RateLimit = defaultRateLimit
+	}
+	if vc.BreakerThreshold <= 0 {
+		vc.BreakerThreshold = defaultBreakerThreshold
+	}
+	if vc.BreakerCooldown <= 0 {
+		vc.BreakerCooldown = defaultBreakerCooldown
+	}
+	if vc.Retry == nil {
+		vc.Retry = &retryConf{}
+	}
+	vc.Retry.setDefaults()
+	if vc.Content != nil {
+		if vc.Content.Top <= 0 {
+			vc.Content.Top = 20
+		}
+		if vc.Content.TitleMaxLength <= 0 {
+			vc.Content.TitleMaxLength = 100
+		}
+	}
+	if vc.Campaigns != nil && vc.Campaigns.Top <= 0 {
+		vc.Campaigns.Top = 50
+	}
+	if vc.GoalValues != nil && vc.GoalValues.Scale == 0 {
+		vc.GoalValues.Scale = 1
+	}
+	if vc.Anomaly != nil {
+		if vc.Anomaly.Weeks <= 0 {
+			vc.Anomaly.Weeks = defaultAnomalyWeeks
+		}
+		if vc.Anomaly.MinSamples <= 0 {
+			vc.Anomaly.MinSamples = defaultAnomalyMinSamples
+		}
+	}
+	for _, b := range vc.Bounds {
+		if b != nil && b.MaxRejections <= 0 {
+			b.MaxRejections = 1
+		}
+	}
+}
+
+// collected reports whether the view has metrics of the RealTime
+// collectors: content, campaigns, device split or goal values.
+func (vc *viewConf) collected() bool {
+	return vc.Content != nil || vc.Campaigns != nil || vc.DeviceSplit || (vc.GoalValues != nil && len(vc.GoalValues.Goals) > 0)
+}
+
+// realtimeWindow returns the period a RealTime metric reports on: users
+// active in the last 5 minutes, everything else over the last 30 minutes.
+func realtimeWindow(metric string) time.Duration {
+	if metric == "rt:activeUsers" {
+		return 5 * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// constLabels returns the labels attached to every metric of the view.
+func (v *view) constLabels() prometheus.Labels {
+	return prometheus.Labels{"job": "googleAnalytics", "viewid": v.ViewID}
+}
+
+// selfLabels returns the labels of the exporter's own metrics of the view,
+// the const labels and its alias when set.
+func (v *view) selfLabels() prometheus.Labels {
+	labels := v.constLabels()
+	if v.Alias != "" {
+		labels["alias"] = v.Alias
+	}
+	return labels
+}
+
+// displayName returns the view as shown in logs and errors, its alias and
+// ID, e.g. "shop (ga:123456789)", its ID without alias.
+func (vc *viewConf) displayName() string {
+	if vc.Alias == "" {
+		return vc.ViewID
+	}
+	return fmt.Sprintf("%s (%s)", vc.Alias, vc.ViewID)
+}
+
+// registerMetricVec returns the GaugeVec of a metric, registering it on
+// first use.
+func (v *view) registerMetricVec(metric string, labels []string) (*prometheus.GaugeVec, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.vecUsed[metric] = time.Now()
+	if vec, ok := v.promGaugeVec[metric]; ok {
+		return vec, nil
+	}
+
+	name, err := v.metricName(metric)
+	if err != nil {
+		return nil, err
+	}
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        name,
+		Help:        fmt.Sprintf("Google Analytics %s", metric),
+		ConstLabels: v.constLabels(),
+	}, labels)
+
+	if err := v.registry.Register(vec); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		if vec, ok = are.ExistingCollector.(*prometheus.GaugeVec); !ok {
+			return nil, fmt.Errorf("%s is already registered with other labels", metric)
+		}
+	}
+	v.promGaugeVec[metric] = vec
+
+	return vec, nil
+}
+
+// registerGauge registers the gauge of a metric without dimensions on its
+// first value.
+func (v *view) registerGauge(metric string, g prometheus.Gauge) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.gaugeSet[metric] {
+		return nil
+	}
+	if err := v.registry.Register(g); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return err
+		}
+	}
+	v.gaugeSet[metric] = true
+	return nil
+}
+
+// compact unregisters the metric vectors not updated since before, e.g. of
+// event actions no longer seen, so they don't accumulate in long running
+// instances. It returns the number of vectors dropped.
+func (v *view) compact(before time.Time) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	n := 0
+	for metric, vec := range v.promGaugeVec {
+		if v.vecUsed[metric].Before(before) {
+			v.registry.Unregister(vec)
+			delete(v.promGaugeVec, metric)
+			delete(v.vecUsed, metric)
+			v.limit.release(len(v.series[metric]))
+			delete(v.series, metric)
+			v.seriesCount.DeleteLabelValues(metric)
+			v.dropChange(metric)
+			n++
+		}
+	}
+	return n
+}
+
+// registerTotal returns the gauge holding the total of a dimensioned metric
+// across all rows, named after the metric with an _all suffix.
+func (v *view) registerTotal(metric string) (prometheus.Gauge, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if g, ok := v.promTotal[metric]; ok {
+		return g, nil
+	}
+
+	name, err := v.metricName(metric)
+	if err != nil {
+		return nil, err
+	}
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        name + "_all",
+		Help:        fmt.Sprintf("Google Analytics %s total across all dimension values", metric),
+		ConstLabels: v.constLabels(),
+	})
+	if err := v.registry.Register(g); err != nil {
+		return nil, err
+	}
+	v.promTotal[metric] = g
+
+	return g, nil
+}
+
+// collectMetric queries GA RealTime API for a specific metric, or
+// breakdown. A metric whose columns are rejected is disabled.
+func (v *view) collectMetric(rts *analytics.DataRealtimeService, metric string, gaDimensions string) error {
+	q := &realtimeQuery{metric: v.queryMetrics(metric), dimensions: gaDimensions}
+	m, err := v.query(rts, q)
+	if err != nil {
+		if reason := rejection(err, q); reason != "" {
+			v.disable(metric, reason)
+		}
+		return err
+	}
+
+	if err := v.export(metric, m); err != nil {
+		return err
+	}
+	v.markFresh(metric)
+	v.stale.WithLabelValues(metric).Set(0)
+	v.diskCache.store(q.signature(v.ViewID), m)
+
+	return nil
+}
+
+// export sets the metrics of a query response, as declared by its mapping
+// or by its breakdown.
+func (v *view) export(metric string, m *analytics.RealtimeData) error {
+	if base, b := v.breakdown(metric); b != nil {
+		return v.exportMapped(metric, m, b.mapping(base, m.ColumnHeaders))
+	}
+	mp, ok := v.Mappings[metric]
+	if !ok {
+		mp = defaultMapping(m.ColumnHeaders)
+	}
+	return v.exportMapped(metric, m, mp)
+}
+
+// realtimeQuery describes a RealTime API query.
+type realtimeQuery struct {
+	metric     string
+	dimensions string
+	sort       string
+	maxResults int64
+	filters    string
+}
+
+// query issues a RealTime API query for the view, subject to its circuit
+// breaker and rate limiter, filtered by its valid hostnames. Identical
+// queries of a cycle are issued once.
+func (v *view) query(rts *analytics.DataRealtimeService, q *realtimeQuery) (*analytics.RealtimeData, error) {
+	q.filters = v.hostnameFilter()
+	return cycleCache.get(q.signature(v.ViewID), func() (*analytics.RealtimeData, error) {
+		m, err := v.fetch(rts, q)
+		// Queries aborted by the cycle timeout are not API errors.
+		if err != nil && v.queryContext().Err() != nil {
+			return m, err
+		}
+		return m, categorize(v.ViewID, v.Alias, err)
+	})
+}
+
+// queryContext returns the context the queries of the view are issued
+// with, that of the running cycle unless the view has its own.
+func (v *view) queryContext() context.Context {
+	if v.ctx != nil {
+		return v.ctx
+	}
+	return cycleContext()
+}
+
+// fetch issues a RealTime API query, retried as the retry policy of the
+// view allows. Only the outcome of the last attempt counts for the circuit
+// breaker, not at all when the query was aborted by the cycle timeout.
+func (v *view) fetch(rts *analytics.DataRealtimeService, q *realtimeQuery) (*analytics.RealtimeData, error) {
+	if !v.breaker.allow() {
+		return nil, errBreakerOpen
+	}
+
+	ctx := v.queryContext()
+	for attempt := 1; ; attempt++ {
+		m, err := v.attempt(ctx, rts, q)
+		if err != nil && ctx.Err() != nil {
+			return m, err
+		}
+		if err == nil || attempt >= v.Retry.MaxAttempts || !v.Retry.retryable(err) {
+			v.breaker.record(err)
+			return m, err
+		}
+		queryRetries.WithLabelValues(v.ViewID, v.Alias).Inc()
+		select {
+		case <-time.After(v.Retry.backoff(attempt)):
+		case <-ctx.Done():
+			return m, err
+		}
+	}
+}
+
+// attempt issues a RealTime API query once.
+func (v *view) attempt(ctx context.Context, rts *analytics.DataRealtimeService, q *realtimeQuery) (*analytics.RealtimeData, error) {
+	if err := v.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	getc := rts.Get(v.ViewID, q.metric).Context(ctx)
+
+	if len(q.dimensions) > 0 {
+		getc.Dimensions(q.dimensions)
+	}
+	if len(q.sort) > 0 {
+		getc.Sort(q.sort)
+	}
+	if len(q.filters) > 0 {
+		getc.Filters(q.filters)
+	}
+	if q.maxResults > 0 {
+		getc.MaxResults(q.maxResults)
+	}
+
+	start := time.Now()
+	m, err := getc.Do()
+	v.usage.request()
+
+	entry := auditEntry{ViewID: v.ViewID, Metric: q.metric, Dimensions: q.dimensions}
+	if m != nil {
+		entry.Rows = len(m.Rows)
+	}
+	auditLog.record(entry, start, err)
+	recordResponse(v.ViewID, q, m, err)
+
+	return m, err
+}
+
+// hostnameFilter returns the RealTime API filter of the valid hostnames,
+// "" when any hostname is valid.
+func (vc *viewConf) hostnameFilter() string {
+	var filters []string
+	for _, h := range vc.ValidHostnames {
+		filters = append(filters, "rt:hostname=="+filterEscaper.Replace(h))
+	}
+	// Filters separated by a comma are OR'ed.
+	return strings.Join(filters, ",")
+}
+
+// filterEscaper escapes the characters special in filter values.
+var filterEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`)
+
+// getDimensions gets dimensions from one specific metric, or breakdown.
+func (v *viewConf) getDimensions(metric string) string {
+	for _, dimensionMap := range v.Dimensions {
+		if dimensions, ok := dimensionMap[metric]; ok {
+			return strings.Join(dimensions, ",")
+		}
+	}
+	if _, b := v.breakdown(metric); b != nil {
+		return strings.Join(b.Dimensions, ",")
+	}
+
+	return ""
+}
+
+// getViews returns the views of the running config. The slice is replaced,
+// not modified, on reload.
+func getViews() []*view {
+	viewsMu.RLock()
+	defer viewsMu.RUnlock()
+	return views
+}
+
+// viewsGatherer gathers the registries of the views of a tenant, or of the
+// views without tenant, as they are when gathered.
+type viewsGatherer string
+
+// Gather implements prometheus.Gatherer.
+func (tenant viewsGatherer) Gather() ([]*dto.MetricFamily, error) {
+	var gs prometheus.Gatherers
+	for _, v := range getViews() {
+		if v.Tenant == string(tenant) {
+			gs = append(gs, v.warm)
+		}
+	}
+	return gs.Gather()
+}
+
+// allGatherers returns the gatherers of all metrics, of every tenant.
+func allGatherers() prometheus.Gatherers {
+	gs := prometheus.Gatherers{prometheus.DefaultGatherer, viewsGatherer("")}
+	for name := range tenants {
+		gs = append(gs, viewsGatherer(name))
+	}
+	return gs
+}
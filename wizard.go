@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/analytics/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// bundle is a preset of metrics offered by the init wizard.
+type bundle struct {
+	description string
+	metrics     []string
+	dimensions  map[string][]string
+	content     bool
+	campaigns   bool
+}
+
+var bundles = map[string]bundle{
+	"basic":     {description: "active users", metrics: []string{"rt:activeUsers"}},
+	"pageviews": {description: "pageviews by device category", metrics: []string{"rt:pageviews"}, dimensions: map[string][]string{"rt:pageviews": {"rt:deviceCategory"}}},
+	"events":    {description: "events by category and action", metrics: []string{"rt:totalEvents"}, dimensions: map[string][]string{"rt:totalEvents": {"rt:eventCategory", "rt:eventAction"}}},
+	"goals":     {description: "goal completions", metrics: []string{"rt:goalCompletionsAll"}},
+	"content":   {description: "active users of the top pages", content: true},
+	"campaigns": {description: "active users by campaign, source and medium", campaigns: true},
+}
+
+// wizardConf is the config written by the init wizard, only the settings
+// it sets.
+type wizardConf struct {
+	Interval int           `yaml:"interval"`
+	PromPort string        `yaml:"promport"`
+	Views    []*wizardView `yaml:"views"`
+}
+
+type wizardView struct {
+	ViewID     string                `yaml:"viewid"`
+	Alias      string                `yaml:"alias,omitempty"`
+	Metrics    []string              `yaml:"metrics,omitempty"`
+	Dimensions []map[string][]string `yaml:"dimensions,omitempty"`
+	Content    map[string]int        `yaml:"content,omitempty"`
+	Campaigns  map[string]int        `yaml:"campaigns,omitempty"`
+}
+
+// profile is a GA view accessible with the credentials.
+type profile struct {
+	id, name, property, account string
+	accountID                   string
+}
+
+// wizard lists the views accessible with the credentials, lets the user
+// pick views and metric bundles, from flags or interactively, and writes a
+// config. It returns the exit status.
+func wizard(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	viewIDs := fs.String("views", "", "Comma separated view IDs, asked for when empty.")
+	names := fs.String("bundles", "", "Comma separated metric bundles, asked for when empty.")
+	out := fs.String("o", "", "File the config is written to, stdout when empty.")
+	interval := fs.Int("interval", 60, "Polling interval in seconds.")
+	port := fs.String("port", "9100", "Port the metrics are served on.")
+	fs.Parse(args)
+
+	as, err := analytics.New(newHTTPClient())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 1
+	}
+	profiles, err := listProfiles(as)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: listing views: %v\n", err)
+		return 1
+	}
+	if len(profiles) == 0 {
+		fmt.Fprintln(os.Stderr, "init: no views are accessible, add the service account email to the Analytics permissions")
+		return 1
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	if *viewIDs == "" {
+		fmt.Fprintln(os.Stderr, "Views:")
+		for i, p := range profiles {
+			fmt.Fprintf(os.Stderr, "%3d) %s  %s / %s / %s\n", i+1, p.id, p.account, p.property, p.name)
+		}
+		if *viewIDs, err = pick(in, "Views to export (numbers, comma separated): ", len(profiles), func(i int) string { return profiles[i].id }); err != nil {
+			fmt.Fprintf(os.Stderr, "init: %v\n", err)
+			return 1
+		}
+	}
+
+	bundleNames := make([]string, 0, len(bundles))
+	for name := range bundles {
+		bundleNames = append(bundleNames, name)
+	}
+	sort.Strings(bundleNames)
+	if *names == "" {
+		fmt.Fprintln(os.Stderr, "Metric bundles:")
+		for i, name := range bundleNames {
+			fmt.Fprintf(os.Stderr, "%3d) %-10s %s\n", i+1, name, bundles[name].description)
+		}
+		if *names, err = pick(in, "Bundles (numbers, comma separated): ", len(bundleNames), func(i int) string { return bundleNames[i] }); err != nil {
+			fmt.Fprintf(os.Stderr, "init: %v\n", err)
+			return 1
+		}
+	}
+
+	// Bundles chosen twice would declare their metrics twice.
+	var chosen []bundle
+	seenBundles := make(map[string]bool)
+	for _, name := range strings.Split(*names, ",") {
+		name = strings.TrimSpace(name)
+		b, ok := bundles[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "init: unknown bundle %q\n", name)
+			return 1
+		}
+		if !seenBundles[name] {
+			seenBundles[name] = true
+			chosen = append(chosen, b)
+		}
+	}
+
+	wc := &wizardConf{Interval: *interval, PromPort: *port}
+	byID := make(map[string]profile)
+	for _, p := range profiles {
+		byID[p.id] = p
+	}
+	seenIDs := make(map[string]bool)
+	aliases := make(map[string]bool)
+	for _, id := range strings.Split(*viewIDs, ",") {
+		id = strings.TrimPrefix(strings.TrimSpace(id), "ga:")
+		p, ok := byID[id]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "init: view %s is not accessible\n", id)
+			return 1
+		}
+		if seenIDs[id] {
+			continue
+		}
+		seenIDs[id] = true
+		// Views of the same name get the aliases name, name_2, name_3...
+		alias := strings.Trim(strings.ToLower(invalidNameChars.ReplaceAllString(p.name, "_")), "_")
+		if alias != "" {
+			unique := alias
+			for n := 2; aliases[unique]; n++ {
+				unique = alias + "_" + strconv.Itoa(n)
+			}
+			alias = unique
+			aliases[alias] = true
+		}
+		wv := &wizardView{ViewID: "ga:" + id, Alias: alias}
+		for _, b := range chosen {
+			wv.Metrics = append(wv.Metrics, b.metrics...)
+			if len(b.dimensions) > 0 {
+				wv.Dimensions = append(wv.Dimensions, b.dimensions)
+			}
+			if b.content {
+				wv.Content = map[string]int{"top": 20}
+			}
+			if b.campaigns {
+				wv.Campaigns = map[string]int{"top": 50}
+			}
+		}
+		wc.Views = append(wc.Views, wv)
+	}
+
+	data, err := yaml.Marshal(wc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 1
+	}
+	if *out == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "config written to %s\n", *out)
+	return 0
+}
+
+// listProfiles returns the views accessible with the credentials.
+func listProfiles(as *analytics.Service) ([]profile, error) {
+	var profiles []profile
+	for start := int64(1); ; {
+		summaries, err := as.Management.AccountSummaries.List().StartIndex(start).MaxResults(1000).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range summaries.Items {
+			for _, wp := range a.WebProperties {
+				for _, p := range wp.Profiles {
+					profiles = append(profiles, profile{id: p.Id, name: p.Name, property: wp.Name, account: a.Name, accountID: a.Id})
+				}
+			}
+		}
+		start += int64(len(summaries.Items))
+		if len(summaries.Items) == 0 || start > summaries.TotalResults {
+			return profiles, nil
+		}
+	}
+}
+
+// pick reads a comma separated list of item numbers from 1 to n and
+// returns the items, comma separated.
+func pick(in *bufio.Reader, prompt string, n int, item func(i int) string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	var items []string
+	for _, f := range strings.Split(line, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		i, err := strconv.Atoi(f)
+		if err != nil || i < 1 || i > n {
+			return "", fmt.Errorf("invalid choice %q", f)
+		}
+		items = append(items, item(i-1))
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("nothing chosen")
+	}
+	return strings.Join(items, ","), nil
+}